@@ -0,0 +1,196 @@
+package azalogger
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+type OverflowPolicy int
+
+const (
+	// BlockOnOverflow blocks the caller until the queue has room
+	BlockOnOverflow OverflowPolicy = iota
+	// DropNewestOnOverflow drops the entry being logged
+	DropNewestOnOverflow
+	// DropOldestOnOverflow drops the oldest queued entry to make room
+	DropOldestOnOverflow
+)
+
+const defaultAsyncBufferSize = 1024
+
+// AsyncConfig enables asynchronous writes to outputs
+// Entries are queued and written by a background goroutine, call Sync() to flush them
+type AsyncConfig struct {
+	// BufferSize is the number of queued entries, defaults to 1024
+	BufferSize int
+	Overflow   OverflowPolicy
+}
+
+// asyncWriter queues every Write, each Write being one encoded entry
+type asyncWriter struct {
+	out      io.Writer
+	overflow OverflowPolicy
+	queue    chan *[]byte
+	flushes  chan chan struct{}
+	buffers  sync.Pool
+	dropped  atomic.Uint64
+	// stop is closed by Close, done once queued entries are written and run returned
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	mu  sync.Mutex
+	err error
+}
+
+func newAsyncWriter(out io.Writer, cfg AsyncConfig) *asyncWriter {
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultAsyncBufferSize
+	}
+
+	w := &asyncWriter{
+		out:      out,
+		overflow: cfg.Overflow,
+		queue:    make(chan *[]byte, bufferSize),
+		flushes:  make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		buffers: sync.Pool{
+			New: func() any {
+				buffer := make([]byte, 0, 512)
+				return &buffer
+			},
+		},
+	}
+	go w.run()
+	return w
+}
+
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	select {
+	case <-w.done:
+		return 0, os.ErrClosed
+	default:
+	}
+
+	buffer := w.buffers.Get().(*[]byte)
+	*buffer = append((*buffer)[:0], p...)
+
+	switch w.overflow {
+	case DropNewestOnOverflow:
+		select {
+		case w.queue <- buffer:
+		default:
+			w.drop(buffer)
+		}
+	case DropOldestOnOverflow:
+		for {
+			select {
+			case w.queue <- buffer:
+				return len(p), nil
+			default:
+			}
+			select {
+			case oldest := <-w.queue:
+				w.drop(oldest)
+			default:
+			}
+		}
+	default:
+		select {
+		case w.queue <- buffer:
+		case <-w.done:
+			w.buffers.Put(buffer)
+			return 0, os.ErrClosed
+		}
+	}
+	return len(p), nil
+}
+
+// Sync writes queued entries, then syncs the output
+// It returns the first write error which happened since last Sync
+func (w *asyncWriter) Sync() error {
+	flushed := make(chan struct{})
+	select {
+	case w.flushes <- flushed:
+		<-flushed
+	case <-w.done:
+	}
+
+	err := w.takeErr()
+	if syncer, ok := w.out.(interface{ Sync() error }); ok {
+		err = errors.Join(err, syncer.Sync())
+	}
+	return err
+}
+
+// Close writes queued entries and stops the background goroutine, the output is left open
+// Later writes fail with os.ErrClosed
+func (w *asyncWriter) Close() error {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+	return w.takeErr()
+}
+
+func (w *asyncWriter) takeErr() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.err
+	w.err = nil
+	return err
+}
+
+func (w *asyncWriter) Dropped() uint64 {
+	if w == nil {
+		return 0
+	}
+	return w.dropped.Load()
+}
+
+func (w *asyncWriter) run() {
+	for {
+		select {
+		case buffer := <-w.queue:
+			w.write(buffer)
+		case flushed := <-w.flushes:
+			w.drain()
+			close(flushed)
+		case <-w.stop:
+			w.drain()
+			close(w.done)
+			return
+		}
+	}
+}
+
+func (w *asyncWriter) drain() {
+	for {
+		select {
+		case buffer := <-w.queue:
+			w.write(buffer)
+		default:
+			return
+		}
+	}
+}
+
+func (w *asyncWriter) write(buffer *[]byte) {
+	if _, err := w.out.Write(*buffer); err != nil {
+		w.mu.Lock()
+		if w.err == nil {
+			w.err = err
+		}
+		w.mu.Unlock()
+	}
+	w.buffers.Put(buffer)
+}
+
+func (w *asyncWriter) drop(buffer *[]byte) {
+	w.dropped.Add(1)
+	w.buffers.Put(buffer)
+}
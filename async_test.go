@@ -0,0 +1,228 @@
+package azalogger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedWriter blocks writes until released
+type gatedWriter struct {
+	started chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	lines   []string
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.release
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+func (w *gatedWriter) Lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.lines...)
+}
+
+// fillQueue writes a first entry taken by the background writer
+// then the given entries in the queue
+func fillQueue(t *testing.T, w *asyncWriter, gate *gatedWriter, entries ...string) {
+	t.Helper()
+
+	_, err := w.Write([]byte("1"))
+	require.NoError(t, err)
+	<-gate.started
+
+	for _, entry := range entries {
+		_, err := w.Write([]byte(entry))
+		require.NoError(t, err)
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestAsyncWriter(t *testing.T) {
+	t.Run("should drop newest on overflow", func(t *testing.T) {
+		gate := newGatedWriter()
+		w := newAsyncWriter(gate, AsyncConfig{BufferSize: 2, Overflow: DropNewestOnOverflow})
+
+		fillQueue(t, w, gate, "2", "3", "4")
+		close(gate.release)
+		require.NoError(t, w.Sync())
+
+		assert.Equal(t, []string{"1", "2", "3"}, gate.Lines())
+		assert.Equal(t, uint64(1), w.Dropped())
+	})
+
+	t.Run("should drop oldest on overflow", func(t *testing.T) {
+		gate := newGatedWriter()
+		w := newAsyncWriter(gate, AsyncConfig{BufferSize: 2, Overflow: DropOldestOnOverflow})
+
+		fillQueue(t, w, gate, "2", "3", "4", "5")
+		close(gate.release)
+		require.NoError(t, w.Sync())
+
+		assert.Equal(t, []string{"1", "4", "5"}, gate.Lines())
+		assert.Equal(t, uint64(2), w.Dropped())
+	})
+
+	t.Run("should block on overflow", func(t *testing.T) {
+		gate := newGatedWriter()
+		w := newAsyncWriter(gate, AsyncConfig{BufferSize: 1, Overflow: BlockOnOverflow})
+
+		fillQueue(t, w, gate, "2")
+		written := make(chan struct{})
+		go func() {
+			_, _ = w.Write([]byte("3"))
+			close(written)
+		}()
+
+		select {
+		case <-written:
+			t.Fatal("write should block while queue is full")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(gate.release)
+		<-written
+		require.NoError(t, w.Sync())
+
+		assert.Equal(t, []string{"1", "2", "3"}, gate.Lines())
+		assert.Equal(t, uint64(0), w.Dropped())
+	})
+
+	t.Run("should copy written bytes", func(t *testing.T) {
+		var buff bytes.Buffer
+		w := newAsyncWriter(&buff, AsyncConfig{})
+
+		p := []byte("first\n")
+		_, err := w.Write(p)
+		require.NoError(t, err)
+		copy(p, "reused")
+		require.NoError(t, w.Sync())
+
+		assert.Equal(t, "first\n", buff.String())
+	})
+
+	t.Run("should report write errors on sync", func(t *testing.T) {
+		w := newAsyncWriter(errWriter{}, AsyncConfig{})
+
+		_, err := w.Write([]byte("lost"))
+		require.NoError(t, err)
+
+		assert.EqualError(t, w.Sync(), "write failed")
+		assert.NoError(t, w.Sync())
+	})
+
+	t.Run("should write queued entries and stop on close", func(t *testing.T) {
+		gate := newGatedWriter()
+		w := newAsyncWriter(gate, AsyncConfig{BufferSize: 1, Overflow: BlockOnOverflow})
+
+		fillQueue(t, w, gate, "2")
+		blocked := make(chan error)
+		go func() {
+			_, err := w.Write([]byte("3"))
+			blocked <- err
+		}()
+		close(gate.release)
+		require.NoError(t, <-blocked)
+
+		require.NoError(t, w.Close())
+		assert.Equal(t, []string{"1", "2", "3"}, gate.Lines())
+		<-w.done
+
+		_, err := w.Write([]byte("closed"))
+		assert.ErrorIs(t, err, os.ErrClosed)
+		assert.NoError(t, w.Sync())
+		assert.NoError(t, w.Close())
+	})
+}
+
+func TestAsync(t *testing.T) {
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	for name, backend := range backends {
+		t.Run("should flush "+name+" logger on sync", func(t *testing.T) {
+			var buff bytes.Buffer
+			logger, err := NewLogger(Config{
+				Backend: backend,
+				Writers: []io.Writer{&buff},
+				Async:   &AsyncConfig{BufferSize: 16},
+			})
+			require.NoError(t, err)
+
+			child := logger.With("app", "myapp")
+			for range 10 {
+				child.Info("async log")
+			}
+			logger.Sync()
+
+			assert.Equal(t, 10, strings.Count(buff.String(), "async log"))
+			assert.Equal(t, uint64(0), logger.(StatsProvider).Stats().AsyncDropped)
+		})
+
+		t.Run("should count dropped entries of "+name+" logger", func(t *testing.T) {
+			gate := newGatedWriter()
+			logger, err := NewLogger(Config{
+				Backend: backend,
+				Writers: []io.Writer{gate},
+				Async:   &AsyncConfig{BufferSize: 1, Overflow: DropNewestOnOverflow},
+			})
+			require.NoError(t, err)
+
+			logger.Info("taken")
+			<-gate.started
+			logger.Info("queued")
+			logger.Info("dropped")
+			close(gate.release)
+			logger.Sync()
+
+			assert.Len(t, gate.Lines(), 2)
+			assert.Equal(t, uint64(1), logger.(StatsProvider).Stats().AsyncDropped)
+			assert.Equal(t, uint64(1), logger.With("foo", "bar").(StatsProvider).Stats().AsyncDropped)
+		})
+
+		t.Run("should drain "+name+" logger on close", func(t *testing.T) {
+			var buff bytes.Buffer
+			logger, err := NewLogger(Config{
+				Backend: backend,
+				Writers: []io.Writer{&buff},
+				Async:   &AsyncConfig{BufferSize: 16},
+			})
+			require.NoError(t, err)
+
+			for range 10 {
+				logger.With("app", "myapp").Info("async log")
+			}
+			closer, ok := logger.(io.Closer)
+			require.True(t, ok)
+			require.NoError(t, closer.Close())
+
+			assert.Equal(t, 10, strings.Count(buff.String(), "async log"))
+			// Sync doesn't block once closed
+			logger.Sync()
+		})
+	}
+}
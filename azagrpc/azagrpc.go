@@ -0,0 +1,283 @@
+// Package azagrpc provides gRPC server and client interceptors logging calls with an azalogger.Logger
+// It's a separate package so that importers of azalogger don't link google.golang.org/grpc
+package azagrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	azalogger "gitlab.com/ludovic-alarcon/aza-logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultCallLogMessage = "call finished"
+
+	// Keys of the server call logger
+	ServiceKey = "grpc_service"
+	MethodKey  = "grpc_method"
+	PeerKey    = "peer"
+
+	// Keys of the client call logger, distinct from server ones
+	// as outgoing calls of a handler are logged with the server call logger
+	ClientServiceKey = "grpc_client_service"
+	ClientMethodKey  = "grpc_client_method"
+	TargetKey        = "grpc_target"
+
+	// Keys of the call log
+	CodeKey             = "grpc_code"
+	MessagesSentKey     = "messages_sent"
+	MessagesReceivedKey = "messages_received"
+)
+
+// InterceptorConfig configures gRPC interceptors, the zero value logs every call
+type InterceptorConfig struct {
+	// DisableCallLog only derives the call logger
+	DisableCallLog bool
+	// CallLogMessage defaults to "call finished"
+	CallLogMessage string
+	// CallLogLevel defaults to info for OK, warn for client errors and error for server ones
+	CallLogLevel func(code codes.Code) azalogger.LogLevel
+	// Skip disables the call log of matching methods, like health checks
+	// fullMethod is formatted as /package.service/method
+	Skip func(fullMethod string) bool
+}
+
+func (cfg InterceptorConfig) withDefaults() InterceptorConfig {
+	if cfg.CallLogMessage == "" {
+		cfg.CallLogMessage = DefaultCallLogMessage
+	}
+	if cfg.CallLogLevel == nil {
+		cfg.CallLogLevel = callLogLevel
+	}
+	return cfg
+}
+
+func callLogLevel(code codes.Code) azalogger.LogLevel {
+	switch code {
+	case codes.OK:
+		return azalogger.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.Unauthenticated, codes.ResourceExhausted, codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return azalogger.WarnLevel
+	default:
+		return azalogger.ErrorLevel
+	}
+}
+
+// UnaryServerInterceptor stores a call logger in the handler context, use azalogger.FromContext in handlers to get it
+// The logger carries service, method and peer address, trace IDs being added by WithContext from the context
+// A call log line with code, latency and message counts is logged once the call is served
+func UnaryServerInterceptor(logger azalogger.Logger, cfg InterceptorConfig) grpc.UnaryServerInterceptor {
+	cfg = cfg.withDefaults()
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx = azalogger.IntoContext(ctx, logger.With(serverCallFields(info.FullMethod, serverPeer(ctx))...))
+
+		resp, err := handler(ctx, req)
+
+		sent := 0
+		if err == nil {
+			sent = 1
+		}
+		logCall(ctx, cfg, info.FullMethod, err, start, sent, 1)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor,
+// the call logger is stored in the stream context
+func StreamServerInterceptor(logger azalogger.Logger, cfg InterceptorConfig) grpc.StreamServerInterceptor {
+	cfg = cfg.withDefaults()
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := azalogger.IntoContext(ss.Context(), logger.With(serverCallFields(info.FullMethod, serverPeer(ss.Context()))...))
+		stream := &serverStream{ServerStream: ss, ctx: ctx}
+
+		err := handler(srv, stream)
+
+		logCall(ctx, cfg, info.FullMethod, err, start, stream.sent, stream.received)
+		return err
+	}
+}
+
+// UnaryClientInterceptor logs outgoing calls with the logger of the call context, stored by IntoContext, or with logger
+// The logger carries client service, method and target, and is stored in the context given to the invoker
+func UnaryClientInterceptor(logger azalogger.Logger, cfg InterceptorConfig) grpc.UnaryClientInterceptor {
+	cfg = cfg.withDefaults()
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		start := time.Now()
+		ctx = azalogger.IntoContext(ctx, clientLogger(ctx, logger).With(clientCallFields(method, cc.Target())...))
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		received := 0
+		if err == nil {
+			received = 1
+		}
+		logCall(ctx, cfg, method, err, start, 1, received)
+		return err
+	}
+}
+
+// StreamClientInterceptor is the streaming counterpart of UnaryClientInterceptor
+// The call is logged once the stream is drained, when RecvMsg returns an error, the stream must be read until then
+func StreamClientInterceptor(logger azalogger.Logger, cfg InterceptorConfig) grpc.StreamClientInterceptor {
+	cfg = cfg.withDefaults()
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx = azalogger.IntoContext(ctx, clientLogger(ctx, logger).With(clientCallFields(method, cc.Target())...))
+
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			logCall(ctx, cfg, method, err, start, 0, 0)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, serverStreams: desc.ServerStreams, finish: func(err error, sent, received int) {
+			logCall(ctx, cfg, method, err, start, sent, received)
+		}}, nil
+	}
+}
+
+func clientLogger(ctx context.Context, logger azalogger.Logger) azalogger.Logger {
+	if contextLogger, ok := azalogger.LoggerFromContext(ctx); ok {
+		return contextLogger
+	}
+	return logger
+}
+
+func serverCallFields(fullMethod, peerAddr string) []any {
+	service, method := splitMethod(fullMethod)
+	return []any{ServiceKey, service, MethodKey, method, PeerKey, peerAddr}
+}
+
+func clientCallFields(fullMethod, target string) []any {
+	service, method := splitMethod(fullMethod)
+	return []any{ClientServiceKey, service, ClientMethodKey, method, TargetKey, target}
+}
+
+// splitMethod splits /package.service/method
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "", service
+	}
+	return service, method
+}
+
+func serverPeer(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+func logCall(ctx context.Context, cfg InterceptorConfig, fullMethod string, err error, start time.Time,
+	sent, received int,
+) {
+	if cfg.DisableCallLog || (cfg.Skip != nil && cfg.Skip(fullMethod)) {
+		return
+	}
+
+	code := status.Code(err)
+	fields := []azalogger.Field{
+		azalogger.String(CodeKey, code.String()),
+		azalogger.Duration(azalogger.LatencyKey, time.Since(start)),
+		azalogger.Int(MessagesSentKey, sent),
+		azalogger.Int(MessagesReceivedKey, received),
+	}
+	if err != nil {
+		fields = append(fields, azalogger.Err(err))
+	}
+	azalogger.FromContext(ctx).Log(cfg.CallLogLevel(code), cfg.CallLogMessage, fields...)
+}
+
+// serverStream overrides the stream context and counts messages
+type serverStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	sent     int
+	received int
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+	}
+	return err
+}
+
+// clientStream counts messages and calls finish once the server closed the stream
+// SendMsg and RecvMsg may be called from different goroutines
+type clientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	finish        func(err error, sent, received int)
+
+	mu       sync.Mutex
+	once     sync.Once
+	sent     int
+	received int
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.mu.Lock()
+		s.sent++
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case errors.Is(err, io.EOF):
+		s.done(nil)
+	case err != nil:
+		s.done(err)
+	default:
+		s.mu.Lock()
+		s.received++
+		s.mu.Unlock()
+		// a single response ends non server streaming calls
+		if !s.serverStreams {
+			s.done(nil)
+		}
+	}
+	return err
+}
+
+func (s *clientStream) done(err error) {
+	s.once.Do(func() {
+		s.mu.Lock()
+		sent, received := s.sent, s.received
+		s.mu.Unlock()
+		s.finish(err, sent, received)
+	})
+}
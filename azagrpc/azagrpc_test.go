@@ -0,0 +1,287 @@
+package azagrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	azalogger "gitlab.com/ludovic-alarcon/aza-logger"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testGRPCServer struct {
+	testpb.UnimplementedTestServiceServer
+	// downstream is called by UnaryCall when set
+	downstream testpb.TestServiceClient
+}
+
+func (s testGRPCServer) UnaryCall(ctx context.Context, req *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	azalogger.FromContext(ctx).Info("handling")
+	if s.downstream != nil {
+		if _, err := s.downstream.UnaryCall(ctx, &testpb.SimpleRequest{}); err != nil {
+			return nil, err
+		}
+	}
+	if req.ResponseStatus != nil {
+		return nil, status.Error(codes.Code(req.ResponseStatus.Code), req.ResponseStatus.Message)
+	}
+	return &testpb.SimpleResponse{}, nil
+}
+
+func (testGRPCServer) StreamingOutputCall(req *testpb.StreamingOutputCallRequest,
+	stream grpc.ServerStreamingServer[testpb.StreamingOutputCallResponse],
+) error {
+	azalogger.FromContext(stream.Context()).Info("streaming")
+	for range req.ResponseParameters {
+		if err := stream.Send(&testpb.StreamingOutputCallResponse{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (testGRPCServer) StreamingInputCall(
+	stream grpc.ClientStreamingServer[testpb.StreamingInputCallRequest, testpb.StreamingInputCallResponse],
+) error {
+	for {
+		_, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&testpb.StreamingInputCallResponse{})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+var testSpanContext = trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}})
+
+// injectSpan stands for the OpenTelemetry gRPC instrumentation
+func injectSpan(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(trace.ContextWithSpanContext(ctx, testSpanContext), req)
+}
+
+// newTestGRPCClient serves srv in process with server interceptors logging to serverLogger
+func newTestGRPCClient(t *testing.T, srv testpb.TestServiceServer, serverLogger, clientLogger azalogger.Logger,
+	cfg InterceptorConfig,
+) testpb.TestServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(injectSpan, UnaryServerInterceptor(serverLogger, cfg)),
+		grpc.StreamInterceptor(StreamServerInterceptor(serverLogger, cfg)),
+	)
+	testpb.RegisterTestServiceServer(server, srv)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(clientLogger, cfg)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(clientLogger, cfg)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return testpb.NewTestServiceClient(conn)
+}
+
+// assertGRPCCall checks the call log, serviceKey being ServiceKey for server calls and ClientServiceKey for client ones
+func assertGRPCCall(t *testing.T, serviceKey string, entry azalogger.LogEntry, level azalogger.LogLevel, code codes.Code,
+	sent, received int,
+) {
+	t.Helper()
+
+	assert.Equal(t, DefaultCallLogMessage, entry.Message)
+	assert.Equal(t, level, entry.Level)
+	for key, expected := range map[string]any{
+		serviceKey:          "grpc.testing.TestService",
+		CodeKey:             code.String(),
+		MessagesSentKey:     sent,
+		MessagesReceivedKey: received,
+	} {
+		field, ok := entry.Field(key)
+		require.True(t, ok, key)
+		assert.Equal(t, expected, field.Value(), key)
+	}
+	_, ok := entry.Field(azalogger.LatencyKey)
+	assert.True(t, ok)
+}
+
+func TestInterceptors(t *testing.T) {
+	t.Run("should log unary calls", func(t *testing.T) {
+		serverLogger, clientLogger := azalogger.NewInMemoryLogger(azalogger.Config{}), azalogger.NewInMemoryLogger(azalogger.Config{})
+		client := newTestGRPCClient(t, testGRPCServer{}, serverLogger, clientLogger, InterceptorConfig{})
+
+		ctx := trace.ContextWithSpanContext(context.Background(), testSpanContext)
+		_, err := client.UnaryCall(ctx, &testpb.SimpleRequest{})
+		require.NoError(t, err)
+
+		entries := serverLogger.FilterField(MethodKey, "UnaryCall")
+		require.Equal(t, 2, entries.Len())
+		assert.Equal(t, "handling", entries[0].Message)
+		assert.Equal(t, testSpanContext.TraceID().String(), entries[0].TraceID)
+		peer, _ := entries[0].Field(PeerKey)
+		assert.Equal(t, "bufconn", peer.Value())
+		assertGRPCCall(t, ServiceKey, entries[1], azalogger.InfoLevel, codes.OK, 1, 1)
+		assert.Equal(t, testSpanContext.TraceID().String(), entries[1].TraceID)
+
+		entries = clientLogger.FilterField(ClientMethodKey, "UnaryCall").FilterField(TargetKey, "passthrough:///bufnet")
+		require.Equal(t, 1, entries.Len())
+		assertGRPCCall(t, ClientServiceKey, entries[0], azalogger.InfoLevel, codes.OK, 1, 1)
+		assert.Equal(t, testSpanContext.TraceID().String(), entries[0].TraceID)
+	})
+
+	t.Run("should choose level from status code", func(t *testing.T) {
+		serverLogger, clientLogger := azalogger.NewInMemoryLogger(azalogger.Config{}), azalogger.NewInMemoryLogger(azalogger.Config{})
+		client := newTestGRPCClient(t, testGRPCServer{}, serverLogger, clientLogger, InterceptorConfig{})
+
+		for code, level := range map[codes.Code]azalogger.LogLevel{codes.NotFound: azalogger.WarnLevel, codes.Internal: azalogger.ErrorLevel} {
+			_, err := client.UnaryCall(context.Background(), &testpb.SimpleRequest{
+				ResponseStatus: &testpb.EchoStatus{Code: int32(code), Message: "boom"},
+			})
+			require.Equal(t, code, status.Code(err))
+
+			entries := serverLogger.FilterField(CodeKey, code.String())
+			require.Equal(t, 1, entries.Len())
+			assertGRPCCall(t, ServiceKey, entries[0], level, code, 0, 1)
+			_, ok := entries[0].Field(azalogger.ErrorKey)
+			assert.True(t, ok)
+
+			entries = clientLogger.FilterField(CodeKey, code.String())
+			require.Equal(t, 1, entries.Len())
+			assertGRPCCall(t, ClientServiceKey, entries[0], level, code, 1, 0)
+		}
+	})
+
+	t.Run("should log streaming calls with message counts", func(t *testing.T) {
+		serverLogger, clientLogger := azalogger.NewInMemoryLogger(azalogger.Config{}), azalogger.NewInMemoryLogger(azalogger.Config{})
+		client := newTestGRPCClient(t, testGRPCServer{}, serverLogger, clientLogger, InterceptorConfig{})
+
+		output, err := client.StreamingOutputCall(context.Background(), &testpb.StreamingOutputCallRequest{
+			ResponseParameters: make([]*testpb.ResponseParameters, 3),
+		})
+		require.NoError(t, err)
+		for {
+			if _, err = output.Recv(); err != nil {
+				break
+			}
+		}
+		require.ErrorIs(t, err, io.EOF)
+
+		input, err := client.StreamingInputCall(context.Background())
+		require.NoError(t, err)
+		for range 2 {
+			require.NoError(t, input.Send(&testpb.StreamingInputCallRequest{}))
+		}
+		_, err = input.CloseAndRecv()
+		require.NoError(t, err)
+
+		entries := serverLogger.FilterField(MethodKey, "StreamingOutputCall")
+		require.Equal(t, 2, entries.Len())
+		assert.Equal(t, "streaming", entries[0].Message)
+		assertGRPCCall(t, ServiceKey, entries[1], azalogger.InfoLevel, codes.OK, 3, 1)
+
+		entries = clientLogger.FilterField(ClientMethodKey, "StreamingOutputCall")
+		require.Equal(t, 1, entries.Len())
+		assertGRPCCall(t, ClientServiceKey, entries[0], azalogger.InfoLevel, codes.OK, 1, 3)
+
+		entries = serverLogger.FilterField(MethodKey, "StreamingInputCall")
+		require.Equal(t, 1, entries.Len())
+		assertGRPCCall(t, ServiceKey, entries[0], azalogger.InfoLevel, codes.OK, 1, 2)
+
+		entries = clientLogger.FilterField(ClientMethodKey, "StreamingInputCall")
+		require.Equal(t, 1, entries.Len())
+		assertGRPCCall(t, ClientServiceKey, entries[0], azalogger.InfoLevel, codes.OK, 2, 1)
+	})
+
+	t.Run("should use client logger from context", func(t *testing.T) {
+		serverLogger, clientLogger := azalogger.NewInMemoryLogger(azalogger.Config{}), azalogger.NewInMemoryLogger(azalogger.Config{})
+		client := newTestGRPCClient(t, testGRPCServer{}, serverLogger, azalogger.NewInMemoryLogger(azalogger.Config{}), InterceptorConfig{})
+
+		ctx := azalogger.IntoContext(context.Background(), clientLogger.With(azalogger.RequestIDKey, "req-1"))
+		_, err := client.UnaryCall(ctx, &testpb.SimpleRequest{})
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, clientLogger.FilterField(azalogger.RequestIDKey, "req-1").FilterField(ClientMethodKey, "UnaryCall").Len())
+	})
+
+	t.Run("should not duplicate keys on outgoing calls of handlers", func(t *testing.T) {
+		serverLogger := azalogger.NewInMemoryLogger(azalogger.Config{})
+		downstream := newTestGRPCClient(t, testGRPCServer{}, azalogger.NewInMemoryLogger(azalogger.Config{}),
+			azalogger.NewInMemoryLogger(azalogger.Config{}), InterceptorConfig{})
+		client := newTestGRPCClient(t, testGRPCServer{downstream: downstream}, serverLogger,
+			azalogger.NewInMemoryLogger(azalogger.Config{}), InterceptorConfig{})
+
+		_, err := client.UnaryCall(context.Background(), &testpb.SimpleRequest{})
+		require.NoError(t, err)
+
+		// the outgoing call is logged with the server call logger of the handler
+		entries := serverLogger.FilterField(ClientMethodKey, "UnaryCall")
+		require.Equal(t, 1, entries.Len())
+		assertGRPCCall(t, ClientServiceKey, entries[0], azalogger.InfoLevel, codes.OK, 1, 1)
+		keys := map[string]bool{}
+		for _, field := range append(entries[0].Context, entries[0].Fields...) {
+			assert.False(t, keys[field.Key], field.Key)
+			keys[field.Key] = true
+		}
+		for _, key := range []string{ServiceKey, MethodKey, PeerKey, TargetKey} {
+			assert.True(t, keys[key], key)
+		}
+	})
+
+	t.Run("should configure call log", func(t *testing.T) {
+		serverLogger, clientLogger := azalogger.NewInMemoryLogger(azalogger.Config{LogLevel: azalogger.DebugLevel}), azalogger.NewInMemoryLogger(azalogger.Config{})
+		client := newTestGRPCClient(t, testGRPCServer{}, serverLogger, clientLogger, InterceptorConfig{
+			CallLogMessage: "rpc",
+			CallLogLevel:   func(codes.Code) azalogger.LogLevel { return azalogger.DebugLevel },
+			Skip:           func(fullMethod string) bool { return fullMethod == testpb.TestService_EmptyCall_FullMethodName },
+		})
+
+		_, err := client.EmptyCall(context.Background(), &testpb.Empty{})
+		require.Equal(t, codes.Unimplemented, status.Code(err))
+		_, err = client.UnaryCall(context.Background(), &testpb.SimpleRequest{})
+		require.NoError(t, err)
+
+		entries := serverLogger.FilterMessage("rpc")
+		require.Equal(t, 1, entries.Len())
+		assert.Equal(t, azalogger.DebugLevel, entries[0].Level)
+		method, _ := entries[0].Field(MethodKey)
+		assert.Equal(t, "UnaryCall", method.Value())
+		assert.Equal(t, 0, clientLogger.Len())
+	})
+
+	t.Run("should only derive call logger", func(t *testing.T) {
+		serverLogger, clientLogger := azalogger.NewInMemoryLogger(azalogger.Config{}), azalogger.NewInMemoryLogger(azalogger.Config{})
+		client := newTestGRPCClient(t, testGRPCServer{}, serverLogger, clientLogger, InterceptorConfig{DisableCallLog: true})
+
+		_, err := client.UnaryCall(context.Background(), &testpb.SimpleRequest{})
+		require.NoError(t, err)
+
+		entries := serverLogger.All()
+		require.Len(t, entries, 1)
+		assert.Equal(t, "handling", entries[0].Message)
+		assert.Equal(t, 0, clientLogger.Len())
+	})
+}
+
+func TestSplitMethod(t *testing.T) {
+	service, method := splitMethod("/grpc.testing.TestService/UnaryCall")
+	assert.Equal(t, "grpc.testing.TestService", service)
+	assert.Equal(t, "UnaryCall", method)
+
+	service, method = splitMethod("unknown")
+	assert.Empty(t, service)
+	assert.Equal(t, "unknown", method)
+}
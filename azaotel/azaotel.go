@@ -0,0 +1,67 @@
+// Package azaotel provides an azalogger.Logger exporting records with OTLP/HTTP
+// It's a separate package so that importers of azalogger don't link the OpenTelemetry SDK, protobuf and gRPC
+package azaotel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	azalogger "gitlab.com/ludovic-alarcon/aza-logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Config configures the OTLP/HTTP exporter
+// Unset options fall back on OTEL_EXPORTER_OTLP_* env vars
+type Config struct {
+	// EndpointURL of the logs receiver like "http://localhost:4318/v1/logs"
+	EndpointURL string
+	Headers     map[string]string
+	// Exporter replaces the OTLP exporter
+	Exporter sdklog.Exporter
+	// ExportInterval of the batch processor, defaults to 1s
+	ExportInterval time.Duration
+}
+
+// NewLogger returns a logger emitting records through an OpenTelemetry SDK provider exporting to otelCfg
+// cfg.ServiceName is added as service.name to the resource, Close shuts the provider down
+func NewLogger(cfg azalogger.Config, otelCfg Config) (azalogger.Logger, error) {
+	exporter := otelCfg.Exporter
+	if exporter == nil {
+		var opts []otlploghttp.Option
+		if otelCfg.EndpointURL != "" {
+			opts = append(opts, otlploghttp.WithEndpointURL(otelCfg.EndpointURL))
+		}
+		if len(otelCfg.Headers) > 0 {
+			opts = append(opts, otlploghttp.WithHeaders(otelCfg.Headers))
+		}
+
+		var err error
+		exporter, err = otlploghttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create OTLP log exporter: %w", err)
+		}
+	}
+
+	res := resource.Default()
+	if cfg.ServiceName != "" {
+		var err error
+		res, err = resource.Merge(res, resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+		if err != nil {
+			return nil, fmt.Errorf("unable to create OpenTelemetry resource: %w", err)
+		}
+	}
+
+	var batchOpts []sdklog.BatchProcessorOption
+	if otelCfg.ExportInterval > 0 {
+		batchOpts = append(batchOpts, sdklog.WithExportInterval(otelCfg.ExportInterval))
+	}
+	provider := sdklog.NewLoggerProvider(
+		sdklog.WithResource(res),
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter, batchOpts...)),
+	)
+	return azalogger.NewOtelLogger(cfg, provider), nil
+}
@@ -0,0 +1,133 @@
+package azaotel
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	azalogger "gitlab.com/ludovic-alarcon/aza-logger"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpReceiver is an in-process OTLP/HTTP logs receiver
+type otlpReceiver struct {
+	mu       sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+	headers  http.Header
+}
+
+func newOTLPReceiver(t *testing.T) (*otlpReceiver, string) {
+	t.Helper()
+
+	receiver := &otlpReceiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req collogspb.ExportLogsServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		receiver.mu.Lock()
+		receiver.requests = append(receiver.requests, &req)
+		receiver.headers = r.Header.Clone()
+		receiver.mu.Unlock()
+
+		resp, _ := proto.Marshal(&collogspb.ExportLogsServiceResponse{})
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(resp)
+	}))
+	t.Cleanup(server.Close)
+	return receiver, server.URL + "/v1/logs"
+}
+
+func (r *otlpReceiver) records() []*logspb.LogRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var records []*logspb.LogRecord
+	for _, req := range r.requests {
+		for _, resourceLogs := range req.GetResourceLogs() {
+			for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+				records = append(records, scopeLogs.GetLogRecords()...)
+			}
+		}
+	}
+	return records
+}
+
+func (r *otlpReceiver) resourceAttribute(key string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, req := range r.requests {
+		for _, resourceLogs := range req.GetResourceLogs() {
+			if value, ok := otlpAttribute(resourceLogs.GetResource().GetAttributes(), key); ok {
+				return value.GetStringValue()
+			}
+		}
+	}
+	return ""
+}
+
+func otlpAttribute(attrs []*commonpb.KeyValue, key string) (*commonpb.AnyValue, bool) {
+	for _, attr := range attrs {
+		if attr.GetKey() == key {
+			return attr.GetValue(), true
+		}
+	}
+	return nil, false
+}
+
+func TestNewLogger(t *testing.T) {
+	t.Run("should export records to the OTLP endpoint with the service resource", func(t *testing.T) {
+		receiver, endpoint := newOTLPReceiver(t)
+		logger, err := NewLogger(azalogger.Config{ServiceName: "my-service"}, Config{
+			EndpointURL: endpoint,
+			Headers:     map[string]string{"X-Tenant": "acme"},
+		})
+		require.NoError(t, err)
+
+		logger.Log(azalogger.WarnLevel, "typed message", azalogger.Duration("latency", time.Millisecond),
+			azalogger.Object("req", azalogger.String("method", "GET")))
+		logger.Sync()
+
+		records := receiver.records()
+		require.Len(t, records, 1)
+		assert.Equal(t, "my-service", receiver.resourceAttribute("service.name"))
+		assert.Equal(t, "acme", receiver.headers.Get("X-Tenant"))
+
+		assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, records[0].GetSeverityNumber())
+		assert.Equal(t, "typed message", records[0].GetBody().GetStringValue())
+		latency, _ := otlpAttribute(records[0].GetAttributes(), "latency")
+		assert.Equal(t, int64(time.Millisecond), latency.GetIntValue())
+		req, _ := otlpAttribute(records[0].GetAttributes(), "req")
+		method, _ := otlpAttribute(req.GetKvlistValue().GetValues(), "method")
+		assert.Equal(t, "GET", method.GetStringValue())
+	})
+
+	t.Run("should export pending records on close", func(t *testing.T) {
+		receiver, endpoint := newOTLPReceiver(t)
+		logger, err := NewLogger(azalogger.Config{}, Config{EndpointURL: endpoint, ExportInterval: time.Hour})
+		require.NoError(t, err)
+
+		logger.Info("pending")
+		closer, ok := logger.(io.Closer)
+		require.True(t, ok)
+		require.NoError(t, closer.Close())
+
+		assert.Len(t, receiver.records(), 1)
+	})
+}
@@ -0,0 +1,142 @@
+package azalogger
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// ContextExtractor returns key/value pairs added by WithContext from ctx
+type ContextExtractor func(ctx context.Context) []any
+
+type contextFieldsKey struct{}
+
+// ContextWithFields returns a copy of ctx carrying kv after the fields already attached to ctx
+// WithContext adds them to the logger on every backend
+func ContextWithFields(ctx context.Context, kv ...any) context.Context {
+	parent := FieldsFromContext(ctx)
+	fields := make([]any, 0, len(parent)+len(kv))
+	fields = append(fields, parent...)
+	fields = append(fields, kv...)
+	return context.WithValue(ctx, contextFieldsKey{}, fields)
+}
+
+// FieldsFromContext returns key/value pairs attached with ContextWithFields
+func FieldsFromContext(ctx context.Context) []any {
+	fields, _ := ctx.Value(contextFieldsKey{}).([]any)
+	return fields
+}
+
+// contextExtractor returns fields added by WithContext, shared by every backend
+// A nil extractor returns span context fields of the canonical schema and ContextWithFields ones
+type contextExtractor struct {
+	// format gives span context keys of the profile
+	format     Format
+	traceState bool
+	baggage    []string
+	// native is set by backends attaching span context to records themselves
+	native         bool
+	spanEventLevel LogLevel
+	extractors     []ContextExtractor
+}
+
+var defaultContextExtractor = &contextExtractor{}
+
+func newContextExtractor(cfg Config, format Format) *contextExtractor {
+	e := &contextExtractor{
+		format:     format,
+		traceState: cfg.Trace.TraceState,
+		baggage:    cfg.Trace.Baggage,
+		extractors: cfg.ContextExtractors,
+	}
+	if isValidLogLevel(cfg.Trace.SpanEventLevel.String()) {
+		e.spanEventLevel = cfg.Trace.SpanEventLevel
+	}
+	return e
+}
+
+// fields returns span context fields, then ContextWithFields ones and the ones of extractors
+// It returns nil when ctx holds none of them
+func (e *contextExtractor) fields(ctx context.Context) []any {
+	if e == nil {
+		e = defaultContextExtractor
+	}
+
+	kv := e.traceFields(ctx)
+	kv = append(kv, FieldsFromContext(ctx)...)
+	for _, extractor := range e.extractors {
+		kv = append(kv, extractor(ctx)...)
+	}
+	return kv
+}
+
+type loggerKey struct{}
+
+// loggerHolder lets atomic.Value store loggers of different concrete types
+type loggerHolder struct {
+	logger Logger
+}
+
+var fallbackLogger atomic.Value
+
+func init() {
+	fallbackLogger.Store(loggerHolder{logger: nopLogger{}})
+}
+
+// SetFallbackLogger sets the logger returned by FromContext when ctx holds none
+// It defaults to a logger discarding entries, a nil logger restores it
+func SetFallbackLogger(logger Logger) {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	fallbackLogger.Store(loggerHolder{logger: logger})
+}
+
+// IntoContext returns a copy of ctx carrying logger
+// Store a logger not derived with WithContext, FromContext applies it
+func IntoContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored with IntoContext, or the fallback one,
+// with WithContext(ctx) applied so trace IDs and context fields are always attached
+func FromContext(ctx context.Context) Logger {
+	logger, ok := LoggerFromContext(ctx)
+	if !ok {
+		logger = fallbackLogger.Load().(loggerHolder).logger
+	}
+	return logger.WithContext(ctx)
+}
+
+// LoggerFromContext returns the logger stored with IntoContext as is, without WithContext applied
+func LoggerFromContext(ctx context.Context) (Logger, bool) {
+	logger, ok := ctx.Value(loggerKey{}).(Logger)
+	return logger, ok
+}
+
+// nopLogger discards entries, it's the default fallback of FromContext
+// Fatal entries still exit like with other backends
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any)                 {}
+func (nopLogger) Info(string, ...any)                  {}
+func (nopLogger) Warn(string, ...any)                  {}
+func (nopLogger) Error(string, ...any)                 {}
+func (nopLogger) Fatal(string, ...any)                 { osExit(1) }
+func (nopLogger) Sync()                                {}
+func (l nopLogger) With(...any) Logger                 { return l }
+func (l nopLogger) WithContext(context.Context) Logger { return l }
+func (nopLogger) LogLevel() string                     { return "" }
+func (nopLogger) Stats() Stats                         { return Stats{} }
+
+func (nopLogger) Log(level LogLevel, _ string, _ ...Field) {
+	if level == FatalLevel {
+		osExit(1)
+	}
+}
+
+func (nopLogger) HTTPLevelHandler(AuthorizationHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "log level control not supported for nop logger", http.StatusNotImplemented)
+	})
+}
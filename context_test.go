@@ -0,0 +1,167 @@
+package azalogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tenantKey struct{}
+
+func tenantExtractor(ctx context.Context) []any {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		return []any{"tenant_id", tenant}
+	}
+	return nil
+}
+
+func TestContextWithFields(t *testing.T) {
+	t.Run("should append fields without changing parent context", func(t *testing.T) {
+		parent := ContextWithFields(context.Background(), "request_id", "req-1")
+		child := ContextWithFields(parent, "user_id", 42)
+
+		assert.Equal(t, []any{"request_id", "req-1"}, FieldsFromContext(parent))
+		assert.Equal(t, []any{"request_id", "req-1", "user_id", 42}, FieldsFromContext(child))
+	})
+
+	t.Run("should return nil without fields", func(t *testing.T) {
+		assert.Nil(t, FieldsFromContext(context.Background()))
+	})
+}
+
+func TestWithContextFields(t *testing.T) {
+	ctx := ContextWithFields(context.Background(), "request_id", "req-1", "password", "secret")
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+	cfg := Config{
+		ContextExtractors: []ContextExtractor{tenantExtractor},
+		Redaction:         &RedactionConfig{Keys: []string{"password"}},
+	}
+
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			var buff bytes.Buffer
+			cfg := cfg
+			cfg.Backend, cfg.Writers = backend, []io.Writer{&buff}
+			logger, err := NewLogger(cfg)
+			require.NoError(t, err)
+
+			logger.WithContext(ctx).Info("handled")
+
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(buff.Bytes(), &entry))
+			assert.Equal(t, "req-1", entry["request_id"])
+			assert.Equal(t, "acme", entry["tenant_id"])
+			assert.Equal(t, defaultRedactionMask, entry["password"])
+			assert.NotContains(t, entry, TraceIDKey)
+		})
+	}
+
+	t.Run("in-memory", func(t *testing.T) {
+		logger := NewInMemoryLogger(cfg)
+
+		logger.WithContext(ctx).Info("handled")
+
+		entries := logger.FilterField("request_id", "req-1").FilterField("tenant_id", "acme").
+			FilterField("password", defaultRedactionMask)
+		assert.Equal(t, 1, entries.Len())
+		assert.Empty(t, entries[0].TraceID)
+	})
+
+	t.Run("otel", func(t *testing.T) {
+		logger, receiver := newTestOtelLogger(t, cfg)
+
+		logger.WithContext(ctx).Info("handled")
+		logger.Sync()
+
+		records := receiver.records()
+		require.Len(t, records, 1)
+		requestID, _ := recordAttribute(records[0], "request_id")
+		assert.Equal(t, "req-1", requestID.AsString())
+		tenantID, _ := recordAttribute(records[0], "tenant_id")
+		assert.Equal(t, "acme", tenantID.AsString())
+		assert.False(t, records[0].TraceID().IsValid())
+	})
+
+	t.Run("should return same logger without context fields", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{ContextExtractors: []ContextExtractor{tenantExtractor}})
+
+		assert.Same(t, logger, logger.WithContext(context.Background()))
+	})
+}
+
+func TestLoggerContext(t *testing.T) {
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	}))
+	ctx = ContextWithFields(ctx, "request_id", "req-1")
+
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			var buff bytes.Buffer
+			logger, err := NewLogger(Config{Backend: backend, Writers: []io.Writer{&buff}})
+			require.NoError(t, err)
+
+			FromContext(IntoContext(ctx, logger.With("app", "myapp"))).Info("handled")
+
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(buff.Bytes(), &entry))
+			assert.Equal(t, "handled", entry[DefaultMessageKey])
+			assert.Equal(t, "myapp", entry["app"])
+			assert.Equal(t, "01000000000000000000000000000000", entry[TraceIDKey])
+			assert.Equal(t, "req-1", entry["request_id"])
+		})
+	}
+
+	t.Run("in-memory", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+
+		FromContext(IntoContext(ctx, logger)).Info("handled")
+
+		entries := logger.FilterMessage("handled").FilterField("request_id", "req-1")
+		require.Equal(t, 1, entries.Len())
+		assert.Equal(t, "01000000000000000000000000000000", entries[0].TraceID)
+	})
+
+	t.Run("should use fallback logger when none is stored", func(t *testing.T) {
+		fallback := NewInMemoryLogger(Config{})
+		SetFallbackLogger(fallback)
+		t.Cleanup(func() { SetFallbackLogger(nil) })
+
+		FromContext(ctx).Info("fallback")
+
+		entries := fallback.FilterMessage("fallback")
+		require.Equal(t, 1, entries.Len())
+		assert.Equal(t, "01000000000000000000000000000000", entries[0].TraceID)
+	})
+
+	t.Run("should discard entries by default", func(t *testing.T) {
+		logger := FromContext(context.Background())
+
+		assert.Equal(t, nopLogger{}, logger)
+		logger.With("app", "myapp").Info("discarded")
+		assert.Equal(t, Stats{}, logger.(StatsProvider).Stats())
+	})
+
+	t.Run("should exit on fatal by default", func(t *testing.T) {
+		var codes []int
+		osExit = func(code int) { codes = append(codes, code) }
+		t.Cleanup(func() { osExit = os.Exit })
+		logger := FromContext(context.Background())
+
+		logger.Fatal("fatal")
+		logger.With("app", "myapp").Log(FatalLevel, "typed fatal")
+		logger.Log(ErrorLevel, "typed error")
+
+		assert.Equal(t, []int{1, 1}, codes)
+	})
+}
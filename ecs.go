@@ -0,0 +1,90 @@
+package azalogger
+
+import (
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Keys of ECSProfile entries, see https://www.elastic.co/guide/en/ecs/current/ecs-field-reference.html
+const (
+	ECSVersion = "8.11.0"
+
+	ECSTimestampKey    = "@timestamp"
+	ECSLevelKey        = "log.level"
+	ECSMessageKey      = "message"
+	ECSOriginKey       = "log.origin"
+	ECSTraceIDKey      = "trace.id"
+	ECSSpanIDKey       = "span.id"
+	ECSServiceNameKey  = "service.name"
+	ECSVersionKey      = "ecs.version"
+	ECSErrorMessageKey = "error.message"
+	ECSStacktraceKey   = "error.stack_trace"
+
+	// ECSTimeFormat is the ISO 8601 layout with milliseconds used by Elastic
+	ECSTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+)
+
+func ecsTraceFields(spanCtx trace.SpanContext) []any {
+	return []any{
+		ECSTraceIDKey, spanCtx.TraceID().String(),
+		ECSSpanIDKey, spanCtx.SpanID().String(),
+	}
+}
+
+func ecsStaticFields(serviceName string) []Field {
+	fields := []Field{String(ECSVersionKey, ECSVersion)}
+	if serviceName != "" {
+		fields = append(fields, String(ECSServiceNameKey, serviceName))
+	}
+	return fields
+}
+
+// ecsSourceFields nests log.origin.file.name, log.origin.file.line and log.origin.function
+func ecsSourceFields(file string, line int, function string) []Field {
+	fields := []Field{Object("file", String("name", file), Int("line", line))}
+	if function != "" {
+		fields = append(fields, String("function", function))
+	}
+	return fields
+}
+
+// ecsCore moves error fields under error.message
+type ecsCore struct {
+	zapcore.Core
+}
+
+func (c *ecsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &ecsCore{Core: c.Core.With(ecsZapFields(fields))}
+}
+
+func (c *ecsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *ecsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, ecsZapFields(fields))
+}
+
+// ecsZapFields encodes errors as their message, without zap errorVerbose field
+// fields are copied only when one of them is renamed
+func ecsZapFields(fields []zapcore.Field) []zapcore.Field {
+	renamed, copied := fields, false
+	for i, field := range fields {
+		if field.Key != ErrorKey {
+			continue
+		}
+		if !copied {
+			renamed, copied = append([]zapcore.Field(nil), fields...), true
+		}
+		if err, ok := field.Interface.(error); ok && field.Type == zapcore.ErrorType {
+			renamed[i] = zap.String(ECSErrorMessageKey, err.Error())
+			continue
+		}
+		renamed[i].Key = ECSErrorMessageKey
+	}
+	return renamed
+}
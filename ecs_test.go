@@ -0,0 +1,101 @@
+package azalogger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ecsFieldNames lists ECS fields emitted by the profile, from the ECS field reference
+var ecsFieldNames = []string{
+	"@timestamp",
+	"ecs.version",
+	"error.message",
+	"error.stack_trace",
+	"log.level",
+	"log.origin.file.line",
+	"log.origin.file.name",
+	"log.origin.function",
+	"message",
+	"service.name",
+	"span.id",
+	"trace.id",
+}
+
+// flattenECS returns dotted keys of entry, like Elasticsearch does
+func flattenECS(prefix string, entry map[string]any, keys map[string]any) {
+	for key, value := range entry {
+		if nested, ok := value.(map[string]any); ok {
+			flattenECS(prefix+key+".", nested, keys)
+			continue
+		}
+		keys[prefix+key] = value
+	}
+}
+
+func TestECSProfile(t *testing.T) {
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	for name, backend := range backends {
+		cfg := Config{
+			Backend:     backend,
+			Env:         ProdEnvironment,
+			LogLevel:    DebugLevel,
+			Profile:     ECSProfile,
+			ServiceName: "my-service",
+		}
+		devCfg := cfg
+		devCfg.Env = DevEnvironment
+
+		t.Run(name, func(t *testing.T) {
+			t.Run("should match golden schema", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, cfg)
+				noFatalExit(t, logger)
+
+				logSchemaScenario(logger)
+
+				assertGolden(t, "schema_ecs", normalizeSchema(t, buff.Bytes(), cfg.format()))
+			})
+
+			t.Run("should only emit ECS fields", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, devCfg)
+				ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID: trace.TraceID{1},
+					SpanID:  trace.SpanID{2},
+				}))
+
+				logger.WithContext(ctx).Error("request failed", "error", errors.New("boom"))
+
+				var entry map[string]any
+				require.NoError(t, json.Unmarshal(buff.Bytes(), &entry))
+				fields := map[string]any{}
+				flattenECS("", entry, fields)
+				assert.ElementsMatch(t, ecsFieldNames, slices.Collect(maps.Keys(fields)))
+				assert.Equal(t, "error", fields[ECSLevelKey])
+				assert.Equal(t, "request failed", fields[ECSMessageKey])
+				assert.Equal(t, "boom", fields[ECSErrorMessageKey])
+				assert.Equal(t, "01000000000000000000000000000000", fields[ECSTraceIDKey])
+				assert.Equal(t, "0200000000000000", fields[ECSSpanIDKey])
+				assert.Equal(t, "my-service", fields[ECSServiceNameKey])
+				assert.Equal(t, ECSVersion, fields[ECSVersionKey])
+				assert.Equal(t, "gitlab.com/ludovic-alarcon/aza-logger.TestECSProfile.func1.2", fields["log.origin.function"])
+			})
+
+			t.Run("should rename typed and injected errors", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, cfg)
+
+				logger.With("error", errors.New("injected")).Log(WarnLevel, "retry", Err(errors.New("typed")))
+
+				assert.Contains(t, buff.String(), `"error.message":"injected"`)
+				assert.Contains(t, buff.String(), `"error.message":"typed"`)
+				assert.NotContains(t, buff.String(), `"error":`)
+			})
+		})
+	}
+}
@@ -10,9 +10,17 @@ var ErrUnsupportedBackend = errors.New("unsupported logger backend")
 func NewLogger(cfg Config) (Logger, error) {
 	switch cfg.Backend {
 	case ZapBackend:
-		return newZapLogger(cfg)
+		logger, err := newZapLogger(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return logger, nil
 	case SlogBackend:
-		return newSlogLogger(cfg), nil
+		logger, err := newSlogLogger(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return logger, nil
 	case InMemoryBackend:
 		fmt.Println("calling NewMemoryLogger(cfg) directly is prefered")
 		return NewInMemoryLogger(cfg), nil
@@ -0,0 +1,129 @@
+package azalogger
+
+import (
+	"math"
+	"time"
+)
+
+type FieldType uint8
+
+const (
+	UnknownType FieldType = iota
+	SkipType
+	StringType
+	IntType
+	Int64Type
+	BoolType
+	Float64Type
+	DurationType
+	TimeType
+	ErrorType
+	AnyType
+	ObjectType
+)
+
+// Field is a strongly typed key/value pair
+// Scalar values are stored without boxing, backends map them
+// to their own typed representation (zap.Field, slog.Attr)
+type Field struct {
+	Key       string
+	Type      FieldType
+	Integer   int64
+	String    string
+	Interface any
+}
+
+func String(key, val string) Field {
+	return Field{Key: key, Type: StringType, String: val}
+}
+
+func Int(key string, val int) Field {
+	return Field{Key: key, Type: IntType, Integer: int64(val)}
+}
+
+func Int64(key string, val int64) Field {
+	return Field{Key: key, Type: Int64Type, Integer: val}
+}
+
+func Bool(key string, val bool) Field {
+	var integer int64
+	if val {
+		integer = 1
+	}
+	return Field{Key: key, Type: BoolType, Integer: integer}
+}
+
+func Float64(key string, val float64) Field {
+	return Field{Key: key, Type: Float64Type, Integer: int64(math.Float64bits(val))}
+}
+
+func Duration(key string, val time.Duration) Field {
+	return Field{Key: key, Type: DurationType, Integer: int64(val)}
+}
+
+func Time(key string, val time.Time) Field {
+	return Field{Key: key, Type: TimeType, Integer: val.UnixNano(), Interface: val.Location()}
+}
+
+// ErrorKey is the key of Err fields
+const ErrorKey = "error"
+
+// Err adds the error under "error" key, a nil error is skipped
+func Err(err error) Field {
+	if err == nil {
+		return Field{Type: SkipType}
+	}
+	return Field{Key: ErrorKey, Type: ErrorType, Interface: err}
+}
+
+// Any falls back on reflection based encoding of the backend
+func Any(key string, val any) Field {
+	return Field{Key: key, Type: AnyType, Interface: val}
+}
+
+// Object nests fields under key
+func Object(key string, fields ...Field) Field {
+	return Field{Key: key, Type: ObjectType, Interface: fields}
+}
+
+// Value returns the field value as an interface
+// Object fields are returned as a map[string]any
+func (f Field) Value() any {
+	switch f.Type {
+	case StringType:
+		return f.String
+	case IntType:
+		return int(f.Integer)
+	case Int64Type:
+		return f.Integer
+	case BoolType:
+		return f.Integer == 1
+	case Float64Type:
+		return math.Float64frombits(uint64(f.Integer))
+	case DurationType:
+		return time.Duration(f.Integer)
+	case TimeType:
+		return f.time()
+	case ObjectType:
+		fields, _ := f.Interface.([]Field)
+		values := make(map[string]any, len(fields))
+		for _, field := range fields {
+			if field.Type != SkipType {
+				values[field.Key] = field.Value()
+			}
+		}
+		return values
+	case SkipType:
+		return nil
+	default:
+		return f.Interface
+	}
+}
+
+func (f Field) time() time.Time {
+	t := time.Unix(0, f.Integer)
+	if loc, ok := f.Interface.(*time.Location); ok && loc != nil {
+		t = t.In(loc)
+	}
+	return t
+}
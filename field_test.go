@@ -0,0 +1,243 @@
+package azalogger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTestZapLogger writes JSON entries to w, zap sampling is disabled so every entry is written
+func newTestZapLogger(tb testing.TB, w io.Writer, level LogLevel) *zapLogger {
+	tb.Helper()
+
+	logger, err := newZapLogger(Config{LogLevel: level, Writers: []io.Writer{w}, Sampling: &SamplingConfig{}})
+	require.NoError(tb, err)
+	return logger
+}
+
+func newTestSlogLogger(tb testing.TB, w io.Writer, level LogLevel) *slogLogger {
+	tb.Helper()
+
+	logger, err := newSlogLogger(Config{LogLevel: level, Writers: []io.Writer{w}})
+	require.NoError(tb, err)
+	return logger
+}
+
+func TestFieldValue(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	err := errors.New("boom")
+
+	testCases := []struct {
+		name     string
+		field    Field
+		expected any
+	}{
+		{name: "string", field: String("k", "v"), expected: "v"},
+		{name: "int", field: Int("k", 42), expected: 42},
+		{name: "int64", field: Int64("k", 42), expected: int64(42)},
+		{name: "bool", field: Bool("k", true), expected: true},
+		{name: "float64", field: Float64("k", 1.5), expected: 1.5},
+		{name: "duration", field: Duration("k", time.Second), expected: time.Second},
+		{name: "time", field: Time("k", now), expected: now},
+		{name: "error", field: Err(err), expected: err},
+		{name: "nil error", field: Err(nil), expected: nil},
+		{name: "any", field: Any("k", []int{1}), expected: []int{1}},
+		{name: "object", field: Object("k", String("a", "b"), Int("c", 1)), expected: map[string]any{"a": "b", "c": 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.field.Value())
+		})
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	fields := []Field{
+		String("app", "myapp"),
+		Int("count", 2),
+		Bool("ok", true),
+		Float64("ratio", 0.5),
+		Duration("latency", time.Millisecond),
+		Err(errors.New("boom")),
+		Err(nil),
+		Object("req", String("method", "GET")),
+	}
+
+	t.Run("should map fields to zap fields", func(t *testing.T) {
+		var buff bytes.Buffer
+		logger := newTestZapLogger(t, &buff, InfoLevel)
+
+		logger.Log(DebugLevel, "filtered")
+		logger.Log(WarnLevel, "typed log", fields...)
+
+		output := buff.String()
+		assert.NotContains(t, output, "filtered")
+		assert.Contains(t, output, `"level":"warn"`)
+		assert.Contains(t, output, `"msg":"typed log","app":"myapp","count":2,"ok":true,"ratio":0.5,"latency":1000000,"error":"boom","req":{"method":"GET"}`)
+	})
+
+	t.Run("should map fields to slog attrs", func(t *testing.T) {
+		var buff bytes.Buffer
+		logger := newTestSlogLogger(t, &buff, InfoLevel)
+
+		logger.Log(DebugLevel, "filtered")
+		logger.Log(WarnLevel, "typed log", fields...)
+		logger.Log("unknown", "unknown level")
+
+		output := buff.String()
+		assert.NotContains(t, output, "filtered")
+		assert.Contains(t, output, `"level":"warn"`)
+		assert.Contains(t, output, `"msg":"typed log","app":"myapp","count":2,"ok":true,"ratio":0.5,"latency":1000000,"error":"boom","req":{"method":"GET"}`)
+		assert.Regexp(t, `"level":"info","caller":"[^"]+","msg":"unknown level"`, output)
+	})
+
+	t.Run("should convert fields for in-memory logger", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{LogLevel: InfoLevel})
+
+		logger.Log(DebugLevel, "filtered")
+		logger.Log(ErrorLevel, "typed log", String("app", "myapp"), Int("count", 2), Err(nil))
+		logger.Log("unknown", "unknown level")
+
+		entries := logger.Entries()
+		require.Len(t, entries, 3)
+		assert.Equal(t, "[ERROR] typed log app=myapp count=2", entries[0])
+		assert.Equal(t, "[INFO] unknown level", entries[1])
+	})
+}
+
+// TestLogFieldsAllocs checks typed fields reach backends without intermediate allocations,
+// backends are called directly as fields passed through the Logger interface always escape
+// requestValues returns runtime values, constants are boxed into any without allocating
+func requestValues(i int) (string, int, time.Duration) {
+	methods := [...]string{"GET", "POST", "PUT", "DELETE"}
+	return methods[i%len(methods)], 200 + i%300, time.Duration(i) * time.Microsecond
+}
+
+func TestLogFieldsAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations vary with the race detector")
+	}
+	err := errors.New("boom")
+
+	t.Run("should allocate as much as zap fields", func(t *testing.T) {
+		logger := newTestZapLogger(t, io.Discard, InfoLevel)
+		method, status, latency := requestValues(1)
+
+		native := testing.AllocsPerRun(100, func() {
+			logger.base.Info("request", zap.String("method", method), zap.Int("status", status),
+				zap.Duration("latency", latency), zap.Error(err))
+		})
+		fields := testing.AllocsPerRun(100, func() {
+			logger.Log(InfoLevel, "request", String("method", method), Int("status", status),
+				Duration("latency", latency), Err(err))
+		})
+		keysAndValues := testing.AllocsPerRun(100, func() {
+			logger.Info("request", "method", method, "status", status, "latency", latency, "error", err)
+		})
+
+		assert.LessOrEqual(t, fields, native)
+		assert.Less(t, fields, keysAndValues)
+	})
+
+	t.Run("should allocate as much as slog attrs", func(t *testing.T) {
+		logger := newTestSlogLogger(t, io.Discard, InfoLevel)
+		ctx := context.Background()
+		method, status, latency := requestValues(1)
+
+		native := testing.AllocsPerRun(100, func() {
+			logger.logger.LogAttrs(ctx, slog.LevelInfo, "request", slog.String("method", method), slog.Int("status", status),
+				slog.Duration("latency", latency), slog.Any("error", err))
+		})
+		fields := testing.AllocsPerRun(100, func() {
+			logger.Log(InfoLevel, "request", String("method", method), Int("status", status),
+				Duration("latency", latency), Err(err))
+		})
+		keysAndValues := testing.AllocsPerRun(100, func() {
+			logger.Info("request", "method", method, "status", status, "latency", latency, "error", err)
+		})
+
+		assert.LessOrEqual(t, fields, native)
+		assert.Less(t, fields, keysAndValues)
+	})
+}
+
+func BenchmarkZapLogger(b *testing.B) {
+	logger := newTestZapLogger(b, io.Discard, InfoLevel)
+	err := errors.New("boom")
+
+	b.Run("keysAndValues", func(b *testing.B) {
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			method, status, latency := requestValues(i)
+			logger.Info("request", "method", method, "status", status, "latency", latency, "error", err)
+			i++
+		}
+	})
+
+	b.Run("fields", func(b *testing.B) {
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			method, status, latency := requestValues(i)
+			logger.Log(InfoLevel, "request", String("method", method), Int("status", status),
+				Duration("latency", latency), Err(err))
+			i++
+		}
+	})
+
+	b.Run("disabled", func(b *testing.B) {
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			method, status, latency := requestValues(i)
+			logger.Debug("request", "method", method, "status", status, "latency", latency)
+			i++
+		}
+	})
+}
+
+func BenchmarkSlogLogger(b *testing.B) {
+	logger := newTestSlogLogger(b, io.Discard, InfoLevel)
+	err := errors.New("boom")
+
+	b.Run("keysAndValues", func(b *testing.B) {
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			method, status, latency := requestValues(i)
+			logger.Info("request", "method", method, "status", status, "latency", latency, "error", err)
+			i++
+		}
+	})
+
+	b.Run("fields", func(b *testing.B) {
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			method, status, latency := requestValues(i)
+			logger.Log(InfoLevel, "request", String("method", method), Int("status", status),
+				Duration("latency", latency), Err(err))
+			i++
+		}
+	})
+
+	b.Run("disabled", func(b *testing.B) {
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			method, status, latency := requestValues(i)
+			logger.Debug("request", "method", method, "status", status, "latency", latency)
+			i++
+		}
+	})
+}
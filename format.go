@@ -0,0 +1,241 @@
+package azalogger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type Encoding string
+
+const (
+	JSONEncoding    Encoding = "json"
+	ConsoleEncoding Encoding = "console"
+	LogfmtEncoding  Encoding = "logfmt"
+)
+
+const (
+	// EpochMillisTimeFormat encodes time as milliseconds since epoch
+	EpochMillisTimeFormat = "epoch_millis"
+	DefaultTimeFormat     = "2006-01-02T15:04:05Z0700"
+
+	DefaultTimeKey    = "timestamp"
+	DefaultLevelKey   = "level"
+	DefaultMessageKey = "msg"
+	CallerKey         = "caller"
+	StacktraceKey     = "stacktrace"
+)
+
+// Format configures how entries are encoded, identically for every backend
+// The zero value gives the canonical schema:
+//   - timestamp: UTC time formatted with DefaultTimeFormat
+//   - level: debug, info, warn, error or fatal
+//   - caller: file:line of the logging call
+//   - msg
+//   - fields in logging order, injected ones first, durations in nanoseconds and times like timestamp
+//   - stacktrace: for error and fatal entries, and warn entries in DevEnvironment
+type Format struct {
+	// Encoding defaults to console in DevEnvironment, json otherwise
+	Encoding Encoding
+	// TimeFormat is a time layout like time.RFC3339Nano or EpochMillisTimeFormat
+	// Times are encoded in UTC
+	TimeFormat string
+	TimeKey    string
+	LevelKey   string
+	MessageKey string
+	// FunctionKey adds the function name of the logging call under this key, empty omits it
+	FunctionKey string
+	// UpperCaseLevel encodes "INFO" instead of "info"
+	UpperCaseLevel bool
+
+	// set from Config by format()
+	profile      Profile
+	gcpProjectID string
+	serviceName  string
+}
+
+func (f Format) withDefaults(env Environment) Format {
+	if f.Encoding == "" {
+		f.Encoding = JSONEncoding
+		if env == DevEnvironment {
+			f.Encoding = ConsoleEncoding
+		}
+	}
+	if f.TimeFormat == "" {
+		f.TimeFormat = DefaultTimeFormat
+	}
+	if f.TimeKey == "" {
+		f.TimeKey = DefaultTimeKey
+	}
+	if f.LevelKey == "" {
+		f.LevelKey = DefaultLevelKey
+	}
+	if f.MessageKey == "" {
+		f.MessageKey = DefaultMessageKey
+	}
+	return f
+}
+
+func (f Format) level(level LogLevel) string {
+	if f.profile == GCPProfile {
+		return gcpSeverity(level)
+	}
+	if f.UpperCaseLevel {
+		return strings.ToUpper(level.String())
+	}
+	return level.String()
+}
+
+// applyZap configures zap encoders
+func (f Format) applyZap(zapCfg *zapcore.EncoderConfig) {
+	zapCfg.TimeKey = f.TimeKey
+	zapCfg.LevelKey = f.LevelKey
+	zapCfg.MessageKey = f.MessageKey
+	zapCfg.NameKey = "logger"
+	zapCfg.CallerKey = CallerKey
+	zapCfg.FunctionKey = f.FunctionKey
+	zapCfg.StacktraceKey = StacktraceKey
+	zapCfg.EncodeCaller = zapcore.ShortCallerEncoder
+	zapCfg.EncodeLevel = func(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(f.level(fromZapLevel(level)))
+	}
+	zapCfg.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		if f.TimeFormat == EpochMillisTimeFormat {
+			enc.AppendInt64(t.UnixMilli())
+			return
+		}
+		enc.AppendString(t.UTC().Format(f.TimeFormat))
+	}
+	// same as slog JSON handler
+	zapCfg.EncodeDuration = zapcore.NanosDurationEncoder
+
+	if f.profile != DefaultProfile {
+		zapCfg.CallerKey = f.callerKey()
+		zapCfg.FunctionKey = ""
+		zapCfg.StacktraceKey = f.stacktraceKey()
+		zapCfg.EncodeCaller = func(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+			// zap JSON encoder is an ArrayEncoder, zap falls back on file:line otherwise
+			if arr, ok := enc.(zapcore.ArrayEncoder); ok {
+				_ = arr.AppendObject(zapFieldsMarshaler(f.sourceFields(caller.File, caller.Line, caller.Function)))
+			}
+		}
+	}
+}
+
+// replaceSlogAttr configures slog JSON handler built-in attributes and time values
+func (f Format) replaceSlogAttr(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Value.Kind() == slog.KindTime {
+		if attr.Key == slog.TimeKey && len(groups) == 0 {
+			attr.Key = f.TimeKey
+		}
+		t := attr.Value.Time()
+		if f.TimeFormat == EpochMillisTimeFormat {
+			return slog.Int64(attr.Key, t.UnixMilli())
+		}
+		return slog.String(attr.Key, t.UTC().Format(f.TimeFormat))
+	}
+	if len(groups) > 0 {
+		return attr
+	}
+
+	switch attr.Key {
+	case slog.LevelKey:
+		level, ok := attr.Value.Any().(slog.Level)
+		if !ok {
+			return attr
+		}
+		return slog.String(f.LevelKey, f.level(fromSlogLevel(level)))
+	case slog.MessageKey:
+		attr.Key = f.MessageKey
+	case ErrorKey:
+		if f.profile == ECSProfile {
+			attr.Key = ECSErrorMessageKey
+		}
+	case slog.SourceKey:
+		source, ok := attr.Value.Any().(*slog.Source)
+		if !ok {
+			return attr
+		}
+		if source.File == "" {
+			return slog.Attr{}
+		}
+		if f.profile != DefaultProfile {
+			return toSlogAttr(Object(f.callerKey(), f.sourceFields(source.File, source.Line, source.Function)...))
+		}
+		caller := slog.String(CallerKey, zapcore.EntryCaller{Defined: true, File: source.File, Line: source.Line}.TrimmedPath())
+		if f.FunctionKey == "" {
+			return caller
+		}
+		// a group without key is inlined
+		return slog.Attr{Value: slog.GroupValue(caller, slog.String(f.FunctionKey, source.Function))}
+	}
+	return attr
+}
+
+// validate returns an error on unknown encoding or profile
+func (f Format) validate() error {
+	if !f.profile.isValid() {
+		return fmt.Errorf("unknown log profile %q", f.profile)
+	}
+
+	switch f.Encoding {
+	case JSONEncoding, ConsoleEncoding, LogfmtEncoding:
+		return nil
+	default:
+		return fmt.Errorf("unknown log encoding %q", f.Encoding)
+	}
+}
+
+// newZapEncoder returns the zap encoder of f.Encoding, cfg being configured by applyZap
+// Console and logfmt ones are shared by both backends, see encoderHandler
+func (f Format) newZapEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	switch f.Encoding {
+	case ConsoleEncoding:
+		return zapcore.NewConsoleEncoder(cfg)
+	case LogfmtEncoding:
+		return newLogfmtEncoder(cfg)
+	default:
+		return newTimeFirstEncoder(cfg)
+	}
+}
+
+type jsonField struct {
+	key   string
+	value json.RawMessage
+}
+
+// parseJSONObject returns fields of a JSON object keeping their order
+func parseJSONObject(data []byte) ([]jsonField, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, errors.New("not a JSON object")
+	}
+
+	fields := make([]jsonField, 0, 8)
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, jsonField{key: key, value: value})
+	}
+	return fields, nil
+}
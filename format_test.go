@@ -0,0 +1,202 @@
+package azalogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			t.Run("should honour key names, time format and level casing", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, Config{Backend: backend, Format: Format{
+					Encoding:       JSONEncoding,
+					TimeFormat:     EpochMillisTimeFormat,
+					TimeKey:        "time",
+					LevelKey:       "severity",
+					MessageKey:     "message",
+					UpperCaseLevel: true,
+				}})
+
+				before := time.Now().UnixMilli()
+				logger.Warn("hello", "user", "bob", "latency", time.Millisecond)
+
+				var entry map[string]any
+				require.NoError(t, json.Unmarshal(buff.Bytes(), &entry))
+				assert.Equal(t, "WARN", entry["severity"])
+				assert.Equal(t, "hello", entry["message"])
+				assert.Equal(t, "bob", entry["user"])
+				assert.InDelta(t, float64(time.Millisecond), entry["latency"], 0)
+				assert.InDelta(t, float64(before), entry["time"], 1000)
+				assert.NotContains(t, entry, "msg")
+				assert.NotContains(t, entry, "level")
+			})
+
+			t.Run("should format time with custom layout", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, Config{Backend: backend, Format: Format{TimeFormat: time.RFC3339Nano}})
+				logger.Info("hello")
+
+				var entry map[string]any
+				require.NoError(t, json.Unmarshal(buff.Bytes(), &entry))
+				assert.Equal(t, "info", entry[DefaultLevelKey])
+				_, err := time.Parse(time.RFC3339Nano, entry[DefaultTimeKey].(string))
+				assert.NoError(t, err)
+			})
+
+			t.Run("should encode logfmt", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, Config{Backend: backend, Format: Format{Encoding: LogfmtEncoding}})
+				logger.Log(InfoLevel, "hello world", String("user", "bob"), Int("count", 2), Object("req", String("method", "GET")))
+
+				assert.Regexp(t, `^timestamp=\S+ level=info (caller=\S+ )?msg="hello world" user=bob count=2 req.method=GET\n$`, buff.String())
+			})
+
+			t.Run("should encode console", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, Config{Backend: backend, Format: Format{Encoding: ConsoleEncoding, UpperCaseLevel: true}})
+				logger.Info("hello", "user", "bob")
+
+				assert.Regexp(t, `^\S+\tINFO\t(\S+\t)?hello\t\{"user": "bob"\}\n$`, buff.String())
+			})
+
+			t.Run("should default to console in dev", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, Config{
+					Backend: backend,
+					Env:     DevEnvironment,
+					Format:  Format{MessageKey: "message"},
+				})
+				logger.Info("hello")
+
+				assert.Regexp(t, `^\S+\tinfo\t(\S+\t)?hello\n$`, buff.String())
+			})
+
+			t.Run("should return an error on unknown encoding", func(t *testing.T) {
+				_, err := NewLogger(Config{Backend: backend, Format: Format{Encoding: "xml"}})
+				assert.ErrorContains(t, err, `unknown log encoding "xml"`)
+			})
+		})
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			t.Run("should write stacktrace on next lines in console", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, Config{Backend: backend, DisableCaller: true, Format: Format{Encoding: ConsoleEncoding}})
+				logger.Error("boom")
+
+				lines := strings.Split(buff.String(), "\n")
+				require.Greater(t, len(lines), 2)
+				assert.Regexp(t, `^\S+\terror\tboom$`, lines[0])
+				assert.Equal(t, "gitlab.com/ludovic-alarcon/aza-logger.TestEncoders.func1.1", lines[1])
+			})
+
+			t.Run("should quote logfmt values when needed", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, Config{Backend: backend, DisableCaller: true, Format: Format{Encoding: LogfmtEncoding}})
+				logger.Info("a=b", "empty", "", "quote", `say "hi"`, "list", []int{1, 2}, "nil", nil, "ok", true, "ratio", 0.5)
+
+				assert.Regexp(t, `^timestamp=\S+ level=info msg="a=b" empty="" quote="say \\"hi\\"" list=\[1,2\] nil=null ok=true ratio=0.5\n$`, buff.String())
+			})
+
+			t.Run("should flatten With and nested fields in logfmt", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, Config{Backend: backend, DisableCaller: true, Format: Format{Encoding: LogfmtEncoding}})
+				logger.With("app", "myapp").Log(InfoLevel, "hello",
+					Object("req", String("method", "GET"), Object("user", Int("id", 1))),
+					Duration("latency", time.Millisecond),
+					Err(errors.New("failed")),
+				)
+
+				assert.Regexp(t, `^timestamp=\S+ level=info msg=hello app=myapp req.method=GET req.user.id=1 latency=1000000 error=failed\n$`, buff.String())
+			})
+		})
+	}
+}
+
+func BenchmarkEncodings(b *testing.B) {
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	encodings := []Encoding{JSONEncoding, ConsoleEncoding, LogfmtEncoding}
+	err := errors.New("boom")
+	for name, backend := range backends {
+		for _, encoding := range encodings {
+			b.Run(name+"/"+string(encoding), func(b *testing.B) {
+				logger, loggerErr := NewLogger(Config{Backend: backend, Writers: []io.Writer{io.Discard}, Sampling: &SamplingConfig{}, Format: Format{Encoding: encoding}})
+				require.NoError(b, loggerErr)
+
+				b.ReportAllocs()
+				i := 0
+				for b.Loop() {
+					method, status, latency := requestValues(i)
+					logger.Log(InfoLevel, "request", String("method", method), Int("status", status),
+						Duration("latency", latency), Err(err))
+					i++
+				}
+			})
+		}
+	}
+}
+
+func TestCaller(t *testing.T) {
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			t.Run("should report caller and function of derived loggers", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, Config{Backend: backend, Format: Format{FunctionKey: "function"}})
+				logger.With("app", "myapp").WithContext(context.Background()).Info("hello")
+
+				var entry map[string]any
+				require.NoError(t, json.Unmarshal(buff.Bytes(), &entry))
+				assert.Regexp(t, `^\w+/format_test\.go:\d+$`, entry[CallerKey])
+				assert.Equal(t, "gitlab.com/ludovic-alarcon/aza-logger.TestCaller.func1.1", entry["function"])
+			})
+
+			t.Run("should report caller of bridged slog records", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, Config{Backend: backend, Format: Format{FunctionKey: "function"}})
+				ToSlog(logger.With("app", "myapp")).Error("hello")
+
+				var entry map[string]any
+				require.NoError(t, json.Unmarshal(buff.Bytes(), &entry))
+				assert.Regexp(t, `^\w+/format_test\.go:\d+$`, entry[CallerKey])
+				assert.Equal(t, "gitlab.com/ludovic-alarcon/aza-logger.TestCaller.func1.2", entry["function"])
+			})
+
+			t.Run("should start stacktrace at the logging call", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, Config{Backend: backend})
+				logger.Error("hello")
+				logger.Log(ErrorLevel, "typed hello")
+				ToSlog(logger).Error("bridged hello")
+
+				lines := bytes.Split(bytes.TrimSpace(buff.Bytes()), []byte("\n"))
+				require.Len(t, lines, 3)
+				for _, line := range lines {
+					var entry map[string]any
+					require.NoError(t, json.Unmarshal(line, &entry))
+					frames := strings.Split(entry[StacktraceKey].(string), "\n")
+					require.Greater(t, len(frames), 2)
+					assert.Equal(t, "gitlab.com/ludovic-alarcon/aza-logger.TestCaller.func1.3", frames[0])
+					assert.Regexp(t, `^\t/.+/format_test\.go:\d+$`, frames[1])
+					assert.Equal(t, "testing.tRunner", frames[2])
+				}
+			})
+
+			t.Run("should disable caller", func(t *testing.T) {
+				var buff bytes.Buffer
+				logger, err := NewLogger(Config{Backend: backend, DisableCaller: true, Writers: []io.Writer{&buff}})
+				require.NoError(t, err)
+				logger.Info("hello")
+				logger.Log(InfoLevel, "typed hello")
+
+				assert.NotContains(t, buff.String(), CallerKey)
+				assert.Contains(t, buff.String(), "typed hello")
+			})
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package azalogger
+
+import (
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Keys of GCPProfile entries, see https://cloud.google.com/logging/docs/structured-logging
+const (
+	GCPTimeKey           = "time"
+	GCPSeverityKey       = "severity"
+	GCPMessageKey        = "message"
+	GCPTraceKey          = "logging.googleapis.com/trace"
+	GCPSpanIDKey         = "logging.googleapis.com/spanId"
+	GCPTraceSampledKey   = "logging.googleapis.com/trace_sampled"
+	GCPSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	HTTPRequestKey       = "httpRequest"
+
+	// GCPProjectEnvVar is read when Config.GCPProjectID is not set
+	GCPProjectEnvVar = "GOOGLE_CLOUD_PROJECT"
+)
+
+func gcpSeverity(level LogLevel) string {
+	switch level {
+	case DebugLevel:
+		return "DEBUG"
+	case WarnLevel:
+		return "WARNING"
+	case ErrorLevel:
+		return "ERROR"
+	case FatalLevel:
+		return "CRITICAL"
+	default:
+		return "INFO"
+	}
+}
+
+// gcpTraceFields formats the trace ID as projects/<id>/traces/<trace> when the project is known
+func gcpTraceFields(spanCtx trace.SpanContext, projectID string) []any {
+	traceID := spanCtx.TraceID().String()
+	if projectID != "" {
+		traceID = "projects/" + projectID + "/traces/" + traceID
+	}
+	return []any{
+		GCPTraceKey, traceID,
+		GCPSpanIDKey, spanCtx.SpanID().String(),
+		GCPTraceSampledKey, spanCtx.IsSampled(),
+	}
+}
+
+// gcpSourceFields encodes line as a string like Cloud Logging does
+func gcpSourceFields(file string, line int, function string) []Field {
+	fields := []Field{String("file", file), String("line", strconv.Itoa(line))}
+	if function != "" {
+		fields = append(fields, String("function", function))
+	}
+	return fields
+}
+
+// HTTPRequest describes a served or sent HTTP request, see HTTPRequestField
+type HTTPRequest struct {
+	Method       string
+	URL          string
+	Status       int
+	RequestSize  int64
+	ResponseSize int64
+	UserAgent    string
+	RemoteIP     string
+	ServerIP     string
+	Referer      string
+	Latency      time.Duration
+	Protocol     string
+}
+
+// HTTPRequestField nests req under "httpRequest" with Cloud Logging key names,
+// so GCPProfile entries are displayed as HTTP requests
+// Zero values are omitted and latency is encoded as seconds like "0.25s"
+func HTTPRequestField(req HTTPRequest) Field {
+	fields := make([]Field, 0, 11)
+	addString := func(key, value string) {
+		if value != "" {
+			fields = append(fields, String(key, value))
+		}
+	}
+
+	addString("requestMethod", req.Method)
+	addString("requestUrl", req.URL)
+	if req.Status != 0 {
+		fields = append(fields, Int("status", req.Status))
+	}
+	// int64 are strings in Cloud Logging JSON representation
+	if req.RequestSize != 0 {
+		addString("requestSize", strconv.FormatInt(req.RequestSize, 10))
+	}
+	if req.ResponseSize != 0 {
+		addString("responseSize", strconv.FormatInt(req.ResponseSize, 10))
+	}
+	addString("userAgent", req.UserAgent)
+	addString("remoteIp", req.RemoteIP)
+	addString("serverIp", req.ServerIP)
+	addString("referer", req.Referer)
+	if req.Latency != 0 {
+		addString("latency", strconv.FormatFloat(req.Latency.Seconds(), 'f', -1, 64)+"s")
+	}
+	addString("protocol", req.Protocol)
+	return Object(HTTPRequestKey, fields...)
+}
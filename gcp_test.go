@@ -0,0 +1,93 @@
+package azalogger
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestGCPProfile(t *testing.T) {
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	for name, backend := range backends {
+		cfg := Config{
+			Backend:      backend,
+			Env:          ProdEnvironment,
+			LogLevel:     DebugLevel,
+			Profile:      GCPProfile,
+			GCPProjectID: "my-project",
+			// replaced by the profile
+			Format: Format{Encoding: LogfmtEncoding, MessageKey: "msg"},
+		}
+
+		t.Run(name, func(t *testing.T) {
+			t.Run("should match golden schema", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, cfg)
+				noFatalExit(t, logger)
+
+				logSchemaScenario(logger)
+
+				assertGolden(t, "schema_gcp", normalizeSchema(t, buff.Bytes(), cfg.format()))
+			})
+
+			t.Run("should map trace context to GCP fields", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, cfg)
+				ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID:    trace.TraceID{1},
+					SpanID:     trace.SpanID{2},
+					TraceFlags: trace.FlagsSampled,
+				}))
+
+				logger.WithContext(ctx).Warn("hello")
+
+				var entry map[string]any
+				require.NoError(t, json.Unmarshal(buff.Bytes(), &entry))
+				assert.Equal(t, "WARNING", entry[GCPSeverityKey])
+				assert.Equal(t, "hello", entry[GCPMessageKey])
+				assert.Equal(t, "projects/my-project/traces/01000000000000000000000000000000", entry[GCPTraceKey])
+				assert.Equal(t, "0200000000000000", entry[GCPSpanIDKey])
+				assert.Equal(t, true, entry[GCPTraceSampledKey])
+				assert.NotContains(t, entry, TraceIDKey)
+			})
+
+			t.Run("should emit httpRequest", func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, cfg)
+
+				logger.Log(InfoLevel, "served", HTTPRequestField(HTTPRequest{
+					Method:       "GET",
+					URL:          "/users",
+					Status:       200,
+					ResponseSize: 512,
+					Latency:      250 * time.Millisecond,
+				}))
+
+				var entry map[string]any
+				require.NoError(t, json.Unmarshal(buff.Bytes(), &entry))
+				assert.Equal(t, map[string]any{
+					"requestMethod": "GET",
+					"requestUrl":    "/users",
+					"status":        float64(200),
+					"responseSize":  "512",
+					"latency":       "0.25s",
+				}, entry[HTTPRequestKey])
+			})
+		})
+	}
+
+	t.Run("should read project from environment", func(t *testing.T) {
+		t.Setenv(GCPProjectEnvVar, "env-project")
+
+		format := Config{Profile: GCPProfile}.format()
+		fields := format.traceFields(trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}}))
+		assert.Equal(t, "projects/env-project/traces/01000000000000000000000000000000", fields[1])
+	})
+
+	t.Run("should return an error on unknown profile", func(t *testing.T) {
+		_, err := NewLogger(Config{Backend: ZapBackend, Profile: "aws"})
+		assert.ErrorContains(t, err, `unknown log profile "aws"`)
+	})
+}
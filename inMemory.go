@@ -1,35 +1,71 @@
 package azalogger
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"net/http"
+	"reflect"
+	"runtime"
 	"strings"
 	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
 )
 
 // InMemoryLogger to be used for unit test
-// Call Buffer() of concret type to get the content of in-memory logs
+// Logs are stored as LogEntry, use All() or the Filter helpers of concret type to query them
+// Child loggers created with With share the same entries with their parent
 type InMemoryLogger struct {
-	buffer         *bytes.Buffer
-	mu             sync.Mutex
+	sink           *inMemorySink
+	id             int
 	logLevel       LogLevel
-	injectedFields []string
+	injectedFields []Field
+	traceID        string
+	spanID         string
+	sampler        *sampler
+	redactor       *redactor
+	extractor      *contextExtractor
+	span           *spanRecorder
 }
 
-func NewInMemoryLogger(cfg Config) *InMemoryLogger {
-	buffer := new(bytes.Buffer)
-	buffer.Grow(1024)
+type inMemorySink struct {
+	mu      sync.Mutex
+	entries LogEntries
+	lastID  int
+}
 
+// LogEntry is a log captured by the in-memory logger
+type LogEntry struct {
+	Level   LogLevel
+	Message string
+	// Fields passed on the log call, in order
+	Fields []Field
+	// Context holds fields injected with With
+	Context []Field
+	Time    time.Time
+	// LoggerID is the ID() of the logger which produced the entry
+	LoggerID int
+	TraceID  string
+	SpanID   string
+	// Caller as trimmed file:line
+	Caller string
+}
+
+type LogEntries []LogEntry
+
+func NewInMemoryLogger(cfg Config) *InMemoryLogger {
 	if !isValidLogLevel(cfg.LogLevel.String()) {
 		cfg.LogLevel = InfoLevel
 	}
 
 	return &InMemoryLogger{
-		buffer:         buffer,
-		logLevel:       cfg.LogLevel,
-		injectedFields: make([]string, 0, 2),
+		sink:      &inMemorySink{entries: make(LogEntries, 0, 16)},
+		logLevel:  cfg.LogLevel,
+		sampler:   newSampler(cfg.Sampling),
+		redactor:  newRedactor(cfg.Redaction),
+		extractor: newContextExtractor(cfg, Format{}),
 	}
 }
 
@@ -44,69 +80,202 @@ func isValidLogLevel(logLevel string) bool {
 }
 
 func (l *InMemoryLogger) Debug(msg string, kv ...any) {
-	if l.logLevel == DebugLevel {
-		l.log("DEBUG", msg, kv...)
+	kv = l.kv(DebugLevel, msg, kv)
+	if l.enabled(DebugLevel) {
+		l.log(DebugLevel, msg, kvToFields(kv))
 	}
 }
 
 func (l *InMemoryLogger) Info(msg string, kv ...any) {
-	if l.logLevel == DebugLevel || l.logLevel == InfoLevel {
-		l.log("INFO", msg, kv...)
+	kv = l.kv(InfoLevel, msg, kv)
+	if l.enabled(InfoLevel) {
+		l.log(InfoLevel, msg, kvToFields(kv))
 	}
 }
 
 func (l *InMemoryLogger) Warn(msg string, kv ...any) {
-	if l.logLevel == DebugLevel ||
-		l.logLevel == InfoLevel ||
-		l.logLevel == WarnLevel {
-		l.log("WARN", msg, kv...)
+	kv = l.kv(WarnLevel, msg, kv)
+	if l.enabled(WarnLevel) {
+		l.log(WarnLevel, msg, kvToFields(kv))
 	}
 }
 
 func (l *InMemoryLogger) Error(msg string, kv ...any) {
-	if l.logLevel == DebugLevel ||
-		l.logLevel == InfoLevel ||
-		l.logLevel == WarnLevel ||
-		l.logLevel == ErrorLevel {
-		l.log("ERROR", msg, kv...)
+	kv = l.kv(ErrorLevel, msg, kv)
+	if l.enabled(ErrorLevel) {
+		l.log(ErrorLevel, msg, kvToFields(kv))
 	}
 }
 
-func (l *InMemoryLogger) Fatal(msg string, kv ...any) { l.log("FATAL", msg, kv...) }
+func (l *InMemoryLogger) Fatal(msg string, kv ...any) {
+	l.log(FatalLevel, msg, kvToFields(l.kv(FatalLevel, msg, kv)))
+}
+
+// kv redacts kv and records them on the span of WithContext
+func (l *InMemoryLogger) kv(level LogLevel, msg string, kv []any) []any {
+	kv = l.redactor.kv(kv)
+	l.span.kv(level, msg, kv)
+	return kv
+}
+
+// Log keeps typed fields as is, unknown level is logged as info
+func (l *InMemoryLogger) Log(level LogLevel, msg string, fields ...Field) {
+	if !isValidLogLevel(level.String()) {
+		level = InfoLevel
+	}
+	fields, _ = l.redactor.fields(fields)
+	l.span.fields(level, msg, fields)
+	if !l.enabled(level) {
+		return
+	}
+
+	nonSkipped := make([]Field, 0, len(fields))
+	for _, field := range fields {
+		if field.Type != SkipType {
+			nonSkipped = append(nonSkipped, field)
+		}
+	}
+	l.log(level, msg, nonSkipped)
+}
+
+func (l *InMemoryLogger) enabled(level LogLevel) bool {
+	return levelSeverity(level) >= levelSeverity(l.logLevel)
+}
+
+func levelSeverity(level LogLevel) int {
+	switch level {
+	case DebugLevel:
+		return 0
+	case WarnLevel:
+		return 2
+	case ErrorLevel:
+		return 3
+	case FatalLevel:
+		return 4
+	default:
+		return 1
+	}
+}
 
 // Sync -> NOOP
 func (l *InMemoryLogger) Sync() {}
 
-func (l *InMemoryLogger) log(level, msg string, kv ...any) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+// Close -> NOOP, entries stay available
+func (l *InMemoryLogger) Close() error { return nil }
 
-	fmt.Fprintf(l.buffer, "[%s] %s", level, msg)
-	for i := 0; i < len(kv); i += 2 {
-		if i+1 < len(kv) {
-			fmt.Fprintf(l.buffer, " %v=%v", kv[i], kv[i+1])
-		}
+// log must be called by exported methods only, the caller being the one calling them
+func (l *InMemoryLogger) log(level LogLevel, msg string, fields []Field) {
+	var pcs [1]uintptr
+	// skip runtime.Callers, log and the exported method of the logger
+	runtime.Callers(3, pcs[:])
+	l.logAt(pcs[0], level, msg, fields)
+}
+
+// logCaller logs kv with pc as caller, see callerLogger
+func (l *InMemoryLogger) logCaller(pc uintptr, level LogLevel, msg string, kv []any) {
+	kv = l.kv(level, msg, kv)
+	if l.enabled(level) {
+		l.logAt(pc, level, msg, kvToFields(kv))
+	}
+}
+
+func (l *InMemoryLogger) logAt(pc uintptr, level LogLevel, msg string, fields []Field) {
+	if !l.sampler.allow(level, msg) {
+		return
+	}
+
+	var caller string
+	if frame := pcFrame(pc); frame.PC != 0 {
+		caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true).TrimmedPath()
 	}
-	for _, field := range l.injectedFields {
-		fmt.Fprintf(l.buffer, " %s", field)
+
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+
+	if stats, ok := l.sampler.summary(); ok && l.enabled(WarnLevel) {
+		l.sink.entries = append(l.sink.entries, LogEntry{
+			Level:    WarnLevel,
+			Message:  droppedSummaryMessage,
+			Fields:   []Field{Any("sampled", stats.Sampled), Any("rate_limited", stats.RateLimited)},
+			Context:  l.injectedFields,
+			Time:     time.Now(),
+			LoggerID: l.id,
+			TraceID:  l.traceID,
+			SpanID:   l.spanID,
+		})
+	}
+
+	l.sink.entries = append(l.sink.entries, LogEntry{
+		Level:    level,
+		Message:  msg,
+		Fields:   fields,
+		Context:  l.injectedFields,
+		Time:     time.Now(),
+		LoggerID: l.id,
+		TraceID:  l.traceID,
+		SpanID:   l.spanID,
+		Caller:   caller,
+	})
+}
+
+// kvToFields ignores the last key when it's not a kv pair
+func kvToFields(kv []any) []Field {
+	fields := make([]Field, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		fields = append(fields, Any(kvKey(kv[i]), kv[i+1]))
 	}
-	l.buffer.WriteByte('\n')
+	return fields
 }
 
+// With returns a child logger with its own ID, parent fields are left untouched
 func (l *InMemoryLogger) With(kv ...any) Logger {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	return l.child(kvToFields(l.redactor.kv(kv)))
+}
 
-	for i := 0; i < len(kv); i += 2 {
-		if i+1 < len(kv) {
-			l.injectedFields = append(l.injectedFields, fmt.Sprintf("%v=%v", kv[i], kv[i+1]))
-		}
+func (l *InMemoryLogger) child(fields []Field) *InMemoryLogger {
+	injectedFields := make([]Field, 0, len(l.injectedFields)+len(fields))
+	injectedFields = append(injectedFields, l.injectedFields...)
+	injectedFields = append(injectedFields, fields...)
+
+	l.sink.mu.Lock()
+	l.sink.lastID++
+	id := l.sink.lastID
+	l.sink.mu.Unlock()
+
+	return &InMemoryLogger{
+		sink:           l.sink,
+		id:             id,
+		logLevel:       l.logLevel,
+		injectedFields: injectedFields,
+		traceID:        l.traceID,
+		spanID:         l.spanID,
+		sampler:        l.sampler,
+		redactor:       l.redactor,
+		extractor:      l.extractor,
+		span:           l.span,
 	}
-	return l
 }
 
+// ID identifies the logger in LogEntry.LoggerID, root logger is 0
+func (l *InMemoryLogger) ID() int {
+	return l.id
+}
+
+// WithContext injects span context and context fields like other backends
+// IDs are also exposed on LogEntry.TraceID and LogEntry.SpanID
 func (l *InMemoryLogger) WithContext(ctx context.Context) Logger {
-	return l
+	kv := l.extractor.fields(ctx)
+	if len(kv) == 0 {
+		return l
+	}
+
+	child := l.child(kvToFields(l.redactor.kv(kv)))
+	child.span = l.extractor.spanRecorder(ctx)
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		child.traceID = spanCtx.TraceID().String()
+		child.spanID = spanCtx.SpanID().String()
+	}
+	return child
 }
 
 func (l *InMemoryLogger) HTTPLevelHandler(authHandler AuthorizationHandler) http.Handler {
@@ -119,11 +288,135 @@ func (l *InMemoryLogger) LogLevel() string {
 	return l.logLevel.String()
 }
 
+func (l *InMemoryLogger) Stats() Stats {
+	return l.sampler.Stats()
+}
+
 // Entries is not part of interface
 // only on concret type in-memory logger
-// allows to get all in-memory logs
+// allows to get all in-memory logs formatted as "[LEVEL] msg k=v"
+// the last element is always empty, prefer All() and the Filter helpers
 func (l *InMemoryLogger) Entries() []string {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	return strings.Split(l.buffer.String(), "\n")
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+
+	lines := make([]string, 0, len(l.sink.entries)+1)
+	for _, entry := range l.sink.entries {
+		lines = append(lines, entry.String())
+	}
+	return append(lines, "")
+}
+
+// All returns a copy of entries captured by the logger and all its children
+func (l *InMemoryLogger) All() LogEntries {
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+
+	return append(LogEntries(nil), l.sink.entries...)
+}
+
+// TakeAll returns captured entries and resets the logger
+func (l *InMemoryLogger) TakeAll() LogEntries {
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+
+	entries := l.sink.entries
+	l.sink.entries = make(LogEntries, 0, 16)
+	return entries
+}
+
+// Reset drops captured entries
+func (l *InMemoryLogger) Reset() {
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+
+	l.sink.entries = make(LogEntries, 0, 16)
+}
+
+func (l *InMemoryLogger) Len() int {
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+
+	return len(l.sink.entries)
+}
+
+func (l *InMemoryLogger) FilterLevel(level LogLevel) LogEntries {
+	return l.All().FilterLevel(level)
+}
+
+func (l *InMemoryLogger) FilterMessage(msg string) LogEntries {
+	return l.All().FilterMessage(msg)
+}
+
+func (l *InMemoryLogger) FilterField(key string, value any) LogEntries {
+	return l.All().FilterField(key, value)
+}
+
+func (e LogEntries) Len() int {
+	return len(e)
+}
+
+// FilterLevel keeps entries logged at exactly level
+func (e LogEntries) FilterLevel(level LogLevel) LogEntries {
+	return e.filter(func(entry LogEntry) bool {
+		return entry.Level == level
+	})
+}
+
+func (e LogEntries) FilterMessage(msg string) LogEntries {
+	return e.filter(func(entry LogEntry) bool {
+		return entry.Message == msg
+	})
+}
+
+// FilterField keeps entries having key with value, in log fields or injected ones
+func (e LogEntries) FilterField(key string, value any) LogEntries {
+	return e.filter(func(entry LogEntry) bool {
+		field, ok := entry.Field(key)
+		return ok && reflect.DeepEqual(field.Value(), value)
+	})
+}
+
+// FilterLogger keeps entries produced by logger, not by its children
+func (e LogEntries) FilterLogger(logger *InMemoryLogger) LogEntries {
+	return e.filter(func(entry LogEntry) bool {
+		return entry.LoggerID == logger.id
+	})
+}
+
+func (e LogEntries) filter(keep func(LogEntry) bool) LogEntries {
+	filtered := make(LogEntries, 0, len(e))
+	for _, entry := range e {
+		if keep(entry) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// Field looks up key in log fields first, then in injected ones
+func (e LogEntry) Field(key string) (Field, bool) {
+	for _, field := range e.Fields {
+		if field.Key == key {
+			return field, true
+		}
+	}
+	for _, field := range e.Context {
+		if field.Key == key {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+func (e LogEntry) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s] %s", strings.ToUpper(e.Level.String()), e.Message)
+	for _, field := range e.Fields {
+		fmt.Fprintf(&sb, " %s=%v", field.Key, field.Value())
+	}
+	for _, field := range e.Context {
+		fmt.Fprintf(&sb, " %s=%v", field.Key, field.Value())
+	}
+	return sb.String()
 }
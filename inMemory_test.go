@@ -3,13 +3,16 @@ package azalogger
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestNewInMemoryLogger(t *testing.T) {
@@ -151,6 +154,17 @@ func TestInMemoryLogger(t *testing.T) {
 		require.Len(t, entries, 2)
 		assert.Equal(t, fmt.Sprintf("%s %s", "[WARN]", expectedLog), entries[0])
 	})
+
+	t.Run("should implement io.Closer and keep entries on close", func(t *testing.T) {
+		logger, err := NewLogger(Config{Backend: InMemoryBackend})
+		require.NoError(t, err)
+		logger.Info("before close")
+
+		closer, ok := logger.(io.Closer)
+		require.True(t, ok)
+		require.NoError(t, closer.Close())
+		assert.Equal(t, 1, logger.(*InMemoryLogger).FilterMessage("before close").Len())
+	})
 }
 
 func TestLogLevel_InMemory(t *testing.T) {
@@ -174,3 +188,157 @@ func TestHttpLevelHandler_InMemory(t *testing.T) {
 
 	assert.Equal(t, rec.Code, http.StatusNotImplemented)
 }
+
+func TestInMemoryLogger_Query(t *testing.T) {
+	newPopulatedLogger := func() *InMemoryLogger {
+		logger := NewInMemoryLogger(Config{LogLevel: DebugLevel}).With("app", "myapp").(*InMemoryLogger)
+		logger.Debug("starting", "step", 1)
+		logger.Info("request", "method", "GET", "status", 200)
+		logger.Log(WarnLevel, "request", String("method", "POST"), Int("status", 500))
+		logger.Error("failed", "error", "boom")
+		return logger
+	}
+
+	t.Run("should store typed entries", func(t *testing.T) {
+		logger := newPopulatedLogger()
+
+		entries := logger.All()
+		require.Equal(t, 4, logger.Len())
+		require.Len(t, entries, 4)
+
+		entry := entries[1]
+		assert.Equal(t, InfoLevel, entry.Level)
+		assert.Equal(t, "request", entry.Message)
+		assert.Equal(t, []Field{Any("method", "GET"), Any("status", 200)}, entry.Fields)
+		assert.Equal(t, []Field{Any("app", "myapp")}, entry.Context)
+		assert.WithinDuration(t, time.Now(), entry.Time, time.Minute)
+		assert.Contains(t, entry.Caller, "inMemory_test.go:")
+		assert.Equal(t, "[INFO] request method=GET status=200 app=myapp", entry.String())
+	})
+
+	t.Run("should filter entries", func(t *testing.T) {
+		logger := newPopulatedLogger()
+
+		assert.Equal(t, 1, logger.FilterLevel(WarnLevel).Len())
+		assert.Equal(t, 2, logger.FilterMessage("request").Len())
+		assert.Equal(t, 0, logger.FilterMessage("unknown").Len())
+		assert.Equal(t, 4, logger.FilterField("app", "myapp").Len())
+		assert.Equal(t, 1, logger.FilterField("status", 500).Len())
+
+		filtered := logger.FilterMessage("request").FilterField("method", "GET")
+		require.Equal(t, 1, filtered.Len())
+		assert.Equal(t, InfoLevel, filtered[0].Level)
+	})
+
+	t.Run("should lookup fields", func(t *testing.T) {
+		entry := newPopulatedLogger().FilterLevel(ErrorLevel)[0]
+
+		field, ok := entry.Field("error")
+		require.True(t, ok)
+		assert.Equal(t, "boom", field.Value())
+
+		field, ok = entry.Field("app")
+		require.True(t, ok)
+		assert.Equal(t, "myapp", field.Value())
+
+		_, ok = entry.Field("unknown")
+		assert.False(t, ok)
+	})
+
+	t.Run("should take all and reset", func(t *testing.T) {
+		logger := newPopulatedLogger()
+
+		entries := logger.TakeAll()
+		assert.Len(t, entries, 4)
+		assert.Equal(t, 0, logger.Len())
+
+		logger.Info("again")
+		assert.Equal(t, 1, logger.Len())
+		logger.Reset()
+		assert.Equal(t, 0, logger.Len())
+		assert.Equal(t, []string{""}, logger.Entries())
+	})
+}
+
+func TestInMemoryLogger_With(t *testing.T) {
+	t.Run("should not mutate parent logger", func(t *testing.T) {
+		parent := NewInMemoryLogger(Config{})
+		child := parent.With("req", 1).(*InMemoryLogger)
+		sibling := parent.With("req", 2).(*InMemoryLogger)
+		grandChild := child.With("user", "bob").(*InMemoryLogger)
+
+		parent.Info("from parent")
+		child.Info("from child")
+		sibling.Info("from sibling")
+		grandChild.Info("from grand child")
+
+		assert.Equal(t, []string{
+			"[INFO] from parent",
+			"[INFO] from child req=1",
+			"[INFO] from sibling req=2",
+			"[INFO] from grand child req=1 user=bob",
+			"",
+		}, parent.Entries())
+	})
+
+	t.Run("should share entries and tell which logger produced them", func(t *testing.T) {
+		parent := NewInMemoryLogger(Config{})
+		child := parent.With("req", 1).(*InMemoryLogger)
+		sibling := parent.With("req", 2).(*InMemoryLogger)
+
+		parent.Info("from parent")
+		child.Info("from child")
+		child.Warn("from child")
+		sibling.Info("from sibling")
+
+		assert.Equal(t, 4, child.Len())
+		assert.Equal(t, 4, sibling.Len())
+		assert.NotEqual(t, child.ID(), sibling.ID())
+		assert.NotEqual(t, parent.ID(), child.ID())
+
+		fromChild := parent.All().FilterLogger(child)
+		require.Len(t, fromChild, 2)
+		assert.Equal(t, child.ID(), fromChild[0].LoggerID)
+		assert.Equal(t, "from child", fromChild[0].Message)
+
+		fromParent := child.All().FilterLogger(parent)
+		require.Len(t, fromParent, 1)
+		assert.Equal(t, "from parent", fromParent[0].Message)
+
+		child.Reset()
+		assert.Equal(t, 0, parent.Len())
+	})
+}
+
+func TestInMemoryLogger_WithContext(t *testing.T) {
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanCtx)
+
+	t.Run("should inject span context", func(t *testing.T) {
+		parent := NewInMemoryLogger(Config{})
+		logger := parent.WithContext(ctx).With("app", "myapp")
+
+		logger.Info("traced log")
+		parent.Info("untraced log")
+
+		entries := parent.All()
+		require.Len(t, entries, 2)
+		assert.Equal(t, spanCtx.TraceID().String(), entries[0].TraceID)
+		assert.Equal(t, spanCtx.SpanID().String(), entries[0].SpanID)
+		assert.Equal(t, fmt.Sprintf("[INFO] traced log trace_id=%s span_id=%s trace_flags=00 sampled=false app=myapp",
+			spanCtx.TraceID(), spanCtx.SpanID()), entries[0].String())
+		assert.Equal(t, 1, parent.FilterField(TraceIDKey, spanCtx.TraceID().String()).Len())
+
+		assert.Empty(t, entries[1].TraceID)
+		assert.Empty(t, entries[1].SpanID)
+	})
+
+	t.Run("should return same logger without valid span", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+
+		assert.Same(t, logger, logger.WithContext(context.Background()))
+	})
+}
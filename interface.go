@@ -24,6 +24,7 @@ package azalogger
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"os"
 )
@@ -57,6 +58,34 @@ type Config struct {
 	LogLevel LogLevel
 	Env      Environment
 	Backend  Backend
+	// Outputs are StdoutOutput, StderrOutput or file paths opened in append mode
+	// When neither Outputs nor Writers are set, every backend writes to stderr
+	Outputs []string
+	// Writers are written alongside Outputs
+	Writers []io.Writer
+	// Rotation applies to file Outputs, nil disables rotation
+	Rotation *Rotation
+	// Sampling applies to every backend, nil keeps backend defaults
+	Sampling *SamplingConfig
+	// Async queues entries written to outputs, nil writes synchronously
+	Async *AsyncConfig
+	// Redaction masks sensitive values on every backend
+	Redaction *RedactionConfig
+	// Format overrides the encoding derived from Env
+	Format Format
+	// DisableCaller removes the caller of the logging call from entries
+	DisableCaller bool
+	// Profile shapes entries for a log ingestion platform, replacing Format
+	Profile Profile
+	// GCPProjectID formats GCPProfile trace IDs as projects/<id>/traces/<trace>
+	// defaults to GOOGLE_CLOUD_PROJECT env var
+	GCPProjectID string
+	// ServiceName is added as service.name by ECSProfile and to the azaotel resource
+	ServiceName string
+	// Trace adds trace state and baggage members in WithContext
+	Trace TraceConfig
+	// ContextExtractors are run by WithContext, their key/value pairs are added to the logger
+	ContextExtractors []ContextExtractor
 }
 
 // Handler to check if the request is allowed to modify log level
@@ -72,6 +101,8 @@ type Logger interface {
 	Warn(msg string, keysAndValues ...any)
 	Error(msg string, keysAndValues ...any)
 	Fatal(msg string, keysAndValues ...any)
+	// Log with strongly typed fields, avoiding the boxing of keysAndValues
+	Log(level LogLevel, msg string, fields ...Field)
 	Sync()
 
 	With(keysAndValues ...any) Logger
@@ -0,0 +1,154 @@
+package azalogger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// levelPayload is the body of HTTPLevelHandler requests and responses
+// TTL is a time.ParseDuration string, a level set with a TTL reverts to the previous one once expired
+type levelPayload struct {
+	Level LogLevel `json:"level"`
+	TTL   string   `json:"ttl,omitempty"`
+}
+
+// levelController serves HTTPLevelHandler for backends with an atomic level, it's shared with derived loggers
+// GET returns the level and the remaining TTL, PUT sets the level, as JSON or form values
+type levelController struct {
+	get func() LogLevel
+	set func(LogLevel)
+
+	mu       sync.Mutex
+	revert   *time.Timer
+	previous LogLevel
+	expires  time.Time
+}
+
+func newZapLevelController(level *zap.AtomicLevel) *levelController {
+	return &levelController{
+		get: func() LogLevel { return LogLevel(level.Level().String()) },
+		set: func(l LogLevel) { level.SetLevel(toZapLevel(l)) },
+	}
+}
+
+func newSlogLevelController(level *slog.LevelVar) *levelController {
+	return &levelController{
+		get: func() LogLevel { return LogLevel(strings.ToLower(level.Level().String())) },
+		set: func(l LogLevel) {
+			slogLevel, _ := parseSlogLevel(l.String())
+			level.Set(slogLevel)
+		},
+	}
+}
+
+func (c *levelController) handler(authHandler AuthorizationHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authHandler != nil && !authHandler(r) {
+			http.Error(w, "unauthorized", http.StatusForbidden)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			payload, err := decodeLevelPayload(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := c.apply(payload); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(c.state())
+	})
+}
+
+func decodeLevelPayload(r *http.Request) (levelPayload, error) {
+	var payload levelPayload
+	if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		payload.Level = LogLevel(r.FormValue("level"))
+		payload.TTL = r.FormValue("ttl")
+		return payload, nil
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		return payload, errors.New("invalid payload")
+	}
+	return payload, nil
+}
+
+// apply sets the level, a level without TTL cancels a pending revert
+// Successive TTLs keep reverting to the level set before the first one
+func (c *levelController) apply(payload levelPayload) error {
+	if !isValidLogLevel(payload.Level.String()) {
+		return fmt.Errorf("invalid log level %q", payload.Level)
+	}
+
+	var ttl time.Duration
+	if payload.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(payload.TTL)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid ttl %q", payload.TTL)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.revert != nil {
+		c.revert.Stop()
+	} else if ttl > 0 {
+		c.previous = c.get()
+	}
+	c.set(payload.Level)
+
+	if ttl == 0 {
+		c.revert = nil
+		return nil
+	}
+
+	var revert *time.Timer
+	revert = time.AfterFunc(ttl, func() { c.expire(revert) })
+	c.revert = revert
+	c.expires = time.Now().Add(ttl)
+	return nil
+}
+
+func (c *levelController) expire(revert *time.Timer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// replaced by a later request
+	if c.revert != revert {
+		return
+	}
+	c.set(c.previous)
+	c.revert = nil
+}
+
+func (c *levelController) state() levelPayload {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	payload := levelPayload{Level: c.get()}
+	if c.revert != nil {
+		payload.TTL = max(time.Until(c.expires), 0).Round(time.Second).String()
+	}
+	return payload
+}
@@ -0,0 +1,114 @@
+package azalogger
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveLevel(t *testing.T, handler http.Handler, method, contentType, body string) (int, levelPayload) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, "/loglevel", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var payload levelPayload
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&payload))
+	}
+	return rec.Code, payload
+}
+
+func TestHTTPLevelHandler(t *testing.T) {
+	newLoggers := map[string]func(t *testing.T) Logger{
+		"zap": func(t *testing.T) Logger {
+			logger, err := NewLogger(Config{Backend: ZapBackend, LogLevel: InfoLevel, Writers: []io.Writer{io.Discard}})
+			require.NoError(t, err)
+			return logger.With("app", "myapp")
+		},
+		"slog": func(t *testing.T) Logger {
+			logger, err := NewLogger(Config{Backend: SlogBackend, LogLevel: InfoLevel, Writers: []io.Writer{io.Discard}})
+			require.NoError(t, err)
+			return logger.With("app", "myapp")
+		},
+		"otel": func(t *testing.T) Logger {
+			logger, _ := newTestOtelLogger(t, Config{LogLevel: InfoLevel})
+			return logger
+		},
+	}
+	for name, newLogger := range newLoggers {
+		newHandler := func(t *testing.T) http.Handler {
+			t.Helper()
+			return newLogger(t).HTTPLevelHandler(nil)
+		}
+
+		t.Run(name+"/should get and set level", func(t *testing.T) {
+			handler := newHandler(t)
+
+			code, got := serveLevel(t, handler, http.MethodGet, "", "")
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, levelPayload{Level: InfoLevel}, got)
+
+			code, got = serveLevel(t, handler, http.MethodPut, "application/json", `{"level":"debug"}`)
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, levelPayload{Level: DebugLevel}, got)
+
+			code, got = serveLevel(t, handler, http.MethodPut, "application/x-www-form-urlencoded", "level=warn")
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, levelPayload{Level: WarnLevel}, got)
+		})
+
+		t.Run(name+"/should report remaining ttl", func(t *testing.T) {
+			handler := newHandler(t)
+
+			code, got := serveLevel(t, handler, http.MethodPut, "application/json", `{"level":"debug","ttl":"15m"}`)
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, levelPayload{Level: DebugLevel, TTL: "15m0s"}, got)
+
+			_, got = serveLevel(t, handler, http.MethodGet, "", "")
+			assert.Equal(t, levelPayload{Level: DebugLevel, TTL: "15m0s"}, got)
+
+			// a level without ttl is kept
+			_, got = serveLevel(t, handler, http.MethodPut, "application/json", `{"level":"warn"}`)
+			assert.Equal(t, levelPayload{Level: WarnLevel}, got)
+		})
+
+		t.Run(name+"/should revert to previous level", func(t *testing.T) {
+			handler := newHandler(t)
+
+			_, got := serveLevel(t, handler, http.MethodPut, "application/json", `{"level":"debug","ttl":"1h"}`)
+			assert.Equal(t, DebugLevel, got.Level)
+			// the first ttl level is restored
+			_, got = serveLevel(t, handler, http.MethodPut, "application/x-www-form-urlencoded", "level=error&ttl=20ms")
+			assert.Equal(t, ErrorLevel, got.Level)
+
+			assert.Eventually(t, func() bool {
+				_, got = serveLevel(t, handler, http.MethodGet, "", "")
+				return got == levelPayload{Level: InfoLevel}
+			}, time.Second, 5*time.Millisecond)
+		})
+
+		t.Run(name+"/should reject invalid requests", func(t *testing.T) {
+			handler := newHandler(t)
+
+			for _, body := range []string{`{"foo"}`, `{"level":"foo"}`, `{"level":"debug","ttl":"foo"}`, `{"level":"debug","ttl":"-1m"}`} {
+				code, _ := serveLevel(t, handler, http.MethodPut, "application/json", body)
+				assert.Equal(t, http.StatusBadRequest, code, body)
+			}
+			code, _ := serveLevel(t, handler, http.MethodPost, "application/json", `{"level":"debug"}`)
+			assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+			_, got := serveLevel(t, handler, http.MethodGet, "", "")
+			assert.Equal(t, levelPayload{Level: InfoLevel}, got)
+		})
+	}
+}
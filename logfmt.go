@@ -0,0 +1,344 @@
+package azalogger
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtPool = buffer.NewPool()
+
+var logfmtEncoderPool = sync.Pool{
+	New: func() any { return &logfmtEncoder{} },
+}
+
+// logfmtEncoder is a zap encoder writing key=value pairs, nested objects being flattened with dots
+// Values are quoted when they contain spaces, quotes or equal signs, arrays are written as JSON
+type logfmtEncoder struct {
+	*zapcore.EncoderConfig
+	buf *buffer.Buffer
+	// prefix of nested object and namespace keys
+	prefix string
+}
+
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	if cfg.LineEnding == "" {
+		cfg.LineEnding = zapcore.DefaultLineEnding
+	}
+	return &logfmtEncoder{EncoderConfig: &cfg, buf: logfmtPool.Get()}
+}
+
+func getLogfmtEncoder(cfg *zapcore.EncoderConfig, prefix string) *logfmtEncoder {
+	enc := logfmtEncoderPool.Get().(*logfmtEncoder)
+	enc.EncoderConfig = cfg
+	enc.buf = logfmtPool.Get()
+	enc.prefix = prefix
+	return enc
+}
+
+func putLogfmtEncoder(enc *logfmtEncoder) {
+	enc.EncoderConfig = nil
+	enc.buf = nil
+	enc.prefix = ""
+	logfmtEncoderPool.Put(enc)
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := &logfmtEncoder{EncoderConfig: e.EncoderConfig, buf: logfmtPool.Get(), prefix: e.prefix}
+	clone.buf.Write(e.buf.Bytes())
+	return clone
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := getLogfmtEncoder(e.EncoderConfig, "")
+
+	if final.TimeKey != "" && !ent.Time.IsZero() {
+		final.addKey(final.TimeKey)
+		final.appendEncoded(func() { final.EncodeTime(ent.Time, final) }, func() { final.AppendInt64(ent.Time.UnixNano()) })
+	}
+	if final.LevelKey != "" && final.EncodeLevel != nil {
+		final.addKey(final.LevelKey)
+		final.appendEncoded(func() { final.EncodeLevel(ent.Level, final) }, func() { final.AppendString(ent.Level.String()) })
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		final.AddString(final.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		if final.CallerKey != "" && final.EncodeCaller != nil {
+			final.addKey(final.CallerKey)
+			final.appendEncoded(func() { final.EncodeCaller(ent.Caller, final) }, func() { final.AppendString(ent.Caller.String()) })
+		}
+		if final.FunctionKey != "" {
+			final.AddString(final.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if final.MessageKey != "" {
+		final.AddString(final.MessageKey, ent.Message)
+	}
+	if e.buf.Len() > 0 {
+		final.separate()
+		final.buf.Write(e.buf.Bytes())
+	}
+
+	final.prefix = e.prefix
+	for _, field := range fields {
+		field.AddTo(final)
+	}
+	final.prefix = ""
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.AddString(final.StacktraceKey, ent.Stack)
+	}
+	final.buf.AppendString(final.LineEnding)
+
+	buf := final.buf
+	putLogfmtEncoder(final)
+	return buf, nil
+}
+
+func (e *logfmtEncoder) separate() {
+	if e.buf.Len() > 0 {
+		e.buf.AppendByte(' ')
+	}
+}
+
+func (e *logfmtEncoder) addKey(key string) {
+	e.separate()
+	e.buf.AppendString(e.prefix)
+	e.buf.AppendString(key)
+	e.buf.AppendByte('=')
+}
+
+// appendEncoded runs a user supplied encoder, writing fallback when it's a no-op
+func (e *logfmtEncoder) appendEncoded(encode, fallback func()) {
+	size := e.buf.Len()
+	encode()
+	if e.buf.Len() == size {
+		fallback()
+	}
+}
+
+func (e *logfmtEncoder) appendString(s string) {
+	if s == "" || strings.ContainsAny(s, " =\"\\\t\r\n") || !utf8.ValidString(s) {
+		e.buf.AppendString(strconv.Quote(s))
+		return
+	}
+	e.buf.AppendString(s)
+}
+
+// addJSON writes the value of field encoded by zap JSON encoder
+// JSON objects are flattened like other objects
+func (e *logfmtEncoder) addJSON(key string, field zapcore.Field) error {
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		EncodeTime:          e.EncodeTime,
+		EncodeDuration:      e.EncodeDuration,
+		NewReflectedEncoder: e.NewReflectedEncoder,
+		SkipLineEnding:      true,
+	})
+	field.Key = ""
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{field})
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	// {"":value}
+	value := buf.Bytes()
+	value = value[len(`{"":`) : len(value)-1]
+	e.addJSONValue(key, value)
+	return nil
+}
+
+func (e *logfmtEncoder) addJSONValue(key string, value json.RawMessage) {
+	if len(value) > 0 && value[0] == '{' {
+		if nested, err := parseJSONObject(value); err == nil {
+			for _, field := range nested {
+				e.addJSONValue(key+"."+field.key, field.value)
+			}
+			return
+		}
+	}
+
+	e.addKey(key)
+	var s string
+	if len(value) > 0 && value[0] == '"' && json.Unmarshal(value, &s) == nil {
+		e.appendString(s)
+		return
+	}
+	e.buf.Write(value)
+}
+
+func (e *logfmtEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	return e.addJSON(key, zap.Array(key, arr))
+}
+
+func (e *logfmtEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	prefix := e.prefix
+	e.prefix = prefix + key + "."
+	err := obj.MarshalLogObject(e)
+	e.prefix = prefix
+	return err
+}
+
+func (e *logfmtEncoder) AddReflected(key string, value any) error {
+	return e.addJSON(key, zap.Reflect(key, value))
+}
+
+func (e *logfmtEncoder) OpenNamespace(key string) {
+	e.prefix += key + "."
+}
+
+func (e *logfmtEncoder) AddBinary(key string, value []byte) {
+	e.AddString(key, base64.StdEncoding.EncodeToString(value))
+}
+
+func (e *logfmtEncoder) AddByteString(key string, value []byte) {
+	e.AddString(key, string(value))
+}
+
+func (e *logfmtEncoder) AddBool(key string, value bool) {
+	e.addKey(key)
+	e.AppendBool(value)
+}
+
+func (e *logfmtEncoder) AddComplex128(key string, value complex128) {
+	e.addKey(key)
+	e.AppendComplex128(value)
+}
+
+func (e *logfmtEncoder) AddComplex64(key string, value complex64) {
+	e.AddComplex128(key, complex128(value))
+}
+
+func (e *logfmtEncoder) AddDuration(key string, value time.Duration) {
+	e.addKey(key)
+	e.AppendDuration(value)
+}
+
+func (e *logfmtEncoder) AddFloat64(key string, value float64) {
+	e.addKey(key)
+	e.AppendFloat64(value)
+}
+
+func (e *logfmtEncoder) AddFloat32(key string, value float32) {
+	e.addKey(key)
+	e.AppendFloat32(value)
+}
+
+func (e *logfmtEncoder) AddInt(key string, value int)     { e.AddInt64(key, int64(value)) }
+func (e *logfmtEncoder) AddInt32(key string, value int32) { e.AddInt64(key, int64(value)) }
+func (e *logfmtEncoder) AddInt16(key string, value int16) { e.AddInt64(key, int64(value)) }
+func (e *logfmtEncoder) AddInt8(key string, value int8)   { e.AddInt64(key, int64(value)) }
+
+func (e *logfmtEncoder) AddInt64(key string, value int64) {
+	e.addKey(key)
+	e.AppendInt64(value)
+}
+
+func (e *logfmtEncoder) AddString(key, value string) {
+	e.addKey(key)
+	e.AppendString(value)
+}
+
+func (e *logfmtEncoder) AddTime(key string, value time.Time) {
+	e.addKey(key)
+	e.AppendTime(value)
+}
+
+func (e *logfmtEncoder) AddUint(key string, value uint)       { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUint32(key string, value uint32)   { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUint16(key string, value uint16)   { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUint8(key string, value uint8)     { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUintptr(key string, value uintptr) { e.AddUint64(key, uint64(value)) }
+
+func (e *logfmtEncoder) AddUint64(key string, value uint64) {
+	e.addKey(key)
+	e.AppendUint64(value)
+}
+
+// Append methods implement zapcore.PrimitiveArrayEncoder for EncodeTime, EncodeLevel, EncodeCaller and EncodeDuration
+
+func (e *logfmtEncoder) AppendBool(value bool) {
+	e.buf.AppendBool(value)
+}
+
+func (e *logfmtEncoder) AppendByteString(value []byte) {
+	e.appendString(string(value))
+}
+
+func (e *logfmtEncoder) AppendComplex128(value complex128) {
+	e.appendString(strconv.FormatComplex(value, 'f', -1, 128))
+}
+
+func (e *logfmtEncoder) AppendComplex64(value complex64) {
+	e.appendString(strconv.FormatComplex(complex128(value), 'f', -1, 64))
+}
+
+func (e *logfmtEncoder) AppendDuration(value time.Duration) {
+	if e.EncodeDuration == nil {
+		e.AppendInt64(int64(value))
+		return
+	}
+	e.appendEncoded(func() { e.EncodeDuration(value, e) }, func() { e.AppendInt64(int64(value)) })
+}
+
+func (e *logfmtEncoder) AppendFloat64(value float64) {
+	e.appendFloat(value, 64)
+}
+
+func (e *logfmtEncoder) AppendFloat32(value float32) {
+	e.appendFloat(float64(value), 32)
+}
+
+// appendFloat writes floats like zap JSON encoder
+func (e *logfmtEncoder) appendFloat(value float64, bitSize int) {
+	switch {
+	case math.IsNaN(value):
+		e.buf.AppendString("NaN")
+	case math.IsInf(value, 1):
+		e.buf.AppendString("+Inf")
+	case math.IsInf(value, -1):
+		e.buf.AppendString("-Inf")
+	default:
+		e.buf.AppendFloat(value, bitSize)
+	}
+}
+
+func (e *logfmtEncoder) AppendInt(value int)     { e.AppendInt64(int64(value)) }
+func (e *logfmtEncoder) AppendInt32(value int32) { e.AppendInt64(int64(value)) }
+func (e *logfmtEncoder) AppendInt16(value int16) { e.AppendInt64(int64(value)) }
+func (e *logfmtEncoder) AppendInt8(value int8)   { e.AppendInt64(int64(value)) }
+
+func (e *logfmtEncoder) AppendInt64(value int64) {
+	e.buf.AppendInt(value)
+}
+
+func (e *logfmtEncoder) AppendString(value string) {
+	e.appendString(value)
+}
+
+func (e *logfmtEncoder) AppendTime(value time.Time) {
+	if e.EncodeTime == nil {
+		e.AppendInt64(value.UnixNano())
+		return
+	}
+	e.appendEncoded(func() { e.EncodeTime(value, e) }, func() { e.AppendInt64(value.UnixNano()) })
+}
+
+func (e *logfmtEncoder) AppendUint(value uint)       { e.AppendUint64(uint64(value)) }
+func (e *logfmtEncoder) AppendUint32(value uint32)   { e.AppendUint64(uint64(value)) }
+func (e *logfmtEncoder) AppendUint16(value uint16)   { e.AppendUint64(uint64(value)) }
+func (e *logfmtEncoder) AppendUint8(value uint8)     { e.AppendUint64(uint64(value)) }
+func (e *logfmtEncoder) AppendUintptr(value uintptr) { e.AppendUint64(uint64(value)) }
+
+func (e *logfmtEncoder) AppendUint64(value uint64) {
+	e.buf.AppendUint(value)
+}
@@ -0,0 +1,152 @@
+package azalogger
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultRequestIDHeader  = "X-Request-ID"
+	DefaultAccessLogMessage = "request served"
+
+	RequestIDKey  = "request_id"
+	MethodKey     = "method"
+	PathKey       = "path"
+	RemoteAddrKey = "remote_addr"
+	StatusKey     = "status"
+	BytesKey      = "bytes"
+	LatencyKey    = "latency"
+)
+
+// HTTPMiddlewareConfig configures HTTPMiddleware, the zero value logs every request
+type HTTPMiddlewareConfig struct {
+	// RequestIDHeader is read from requests and set on responses, defaults to X-Request-ID
+	// A request ID is generated when the request has none
+	RequestIDHeader string
+	// DisableAccessLog only derives the request logger
+	DisableAccessLog bool
+	// AccessLogMessage defaults to "request served"
+	AccessLogMessage string
+	// AccessLogLevel defaults to error for 5xx, warn for 4xx and info otherwise
+	AccessLogLevel func(status int) LogLevel
+	// Skip disables the access log of matching requests, like health checks
+	Skip func(r *http.Request) bool
+}
+
+func (cfg HTTPMiddlewareConfig) withDefaults() HTTPMiddlewareConfig {
+	if cfg.RequestIDHeader == "" {
+		cfg.RequestIDHeader = DefaultRequestIDHeader
+	}
+	if cfg.AccessLogMessage == "" {
+		cfg.AccessLogMessage = DefaultAccessLogMessage
+	}
+	if cfg.AccessLogLevel == nil {
+		cfg.AccessLogLevel = accessLogLevel
+	}
+	return cfg
+}
+
+func accessLogLevel(status int) LogLevel {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return ErrorLevel
+	case status >= http.StatusBadRequest:
+		return WarnLevel
+	default:
+		return InfoLevel
+	}
+}
+
+// HTTPMiddleware stores a request logger in the request context, use FromContext in handlers to get it
+// The logger carries method, path and remote address, the request ID and trace IDs being added
+// by WithContext from the context, so they're propagated downstream
+// An access log line with status, bytes written and latency is logged once the request is served
+func HTTPMiddleware(logger Logger, cfg HTTPMiddlewareConfig) func(http.Handler) http.Handler {
+	cfg = cfg.withDefaults()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get(cfg.RequestIDHeader)
+			if requestID == "" {
+				requestID = newRequestID()
+			}
+			w.Header().Set(cfg.RequestIDHeader, requestID)
+
+			ctx := ContextWithFields(r.Context(), RequestIDKey, requestID)
+			ctx = IntoContext(ctx, logger.With(MethodKey, r.Method, PathKey, r.URL.Path, RemoteAddrKey, r.RemoteAddr))
+			r = r.WithContext(ctx)
+
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			if cfg.DisableAccessLog || (cfg.Skip != nil && cfg.Skip(r)) {
+				return
+			}
+			FromContext(ctx).Log(cfg.AccessLogLevel(rw.status), cfg.AccessLogMessage,
+				Int(StatusKey, rw.status),
+				Int64(BytesKey, rw.bytes),
+				Duration(LatencyKey, time.Since(start)),
+			)
+		})
+	}
+}
+
+func newRequestID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// responseWriter records status and bytes written
+// Flush and Hijack are delegated to the wrapped writer, Unwrap supports http.ResponseController
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		// informational headers are followed by the final one
+		w.wroteHeader = status >= http.StatusOK || status == http.StatusSwitchingProtocols
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		flusher.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err == nil && !w.wroteHeader {
+		w.status, w.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return conn, rw, err
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,141 @@
+package azalogger
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	t.Run("should store request logger in context and log access", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+		handler := HTTPMiddleware(logger, HTTPMiddlewareConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			FromContext(r.Context()).Info("handling")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("created"))
+		}))
+
+		spanCtx := trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}})
+		req := httptest.NewRequest(http.MethodPost, "/users?id=1", nil)
+		req = req.WithContext(trace.ContextWithSpanContext(req.Context(), spanCtx))
+		req.Header.Set(DefaultRequestIDHeader, "req-1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, "req-1", rec.Header().Get(DefaultRequestIDHeader))
+		entries := logger.FilterField(RequestIDKey, "req-1").FilterField(MethodKey, http.MethodPost).
+			FilterField(PathKey, "/users").FilterField(RemoteAddrKey, req.RemoteAddr)
+		require.Equal(t, 2, entries.Len())
+		assert.Equal(t, "handling", entries[0].Message)
+		assert.Equal(t, spanCtx.TraceID().String(), entries[0].TraceID)
+
+		access := entries[1]
+		assert.Equal(t, DefaultAccessLogMessage, access.Message)
+		assert.Equal(t, InfoLevel, access.Level)
+		assert.Equal(t, spanCtx.TraceID().String(), access.TraceID)
+		status, _ := access.Field(StatusKey)
+		assert.Equal(t, http.StatusCreated, status.Value())
+		bytes, _ := access.Field(BytesKey)
+		assert.Equal(t, int64(len("created")), bytes.Value())
+		latency, _ := access.Field(LatencyKey)
+		assert.IsType(t, time.Duration(0), latency.Value())
+	})
+
+	t.Run("should generate request ID", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+		handler := HTTPMiddleware(logger, HTTPMiddlewareConfig{RequestIDHeader: "X-Correlation-ID"})(http.NotFoundHandler())
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		requestID := rec.Header().Get("X-Correlation-ID")
+		assert.Len(t, requestID, 32)
+		entries := logger.FilterField(RequestIDKey, requestID)
+		require.Equal(t, 1, entries.Len())
+		assert.Equal(t, WarnLevel, entries[0].Level)
+	})
+
+	t.Run("should configure access log", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{LogLevel: DebugLevel})
+		handler := HTTPMiddleware(logger, HTTPMiddlewareConfig{
+			AccessLogMessage: "access",
+			AccessLogLevel:   func(int) LogLevel { return DebugLevel },
+			Skip:             func(r *http.Request) bool { return r.URL.Path == "/healthz" },
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+		entries := logger.All()
+		require.Len(t, entries, 1)
+		assert.Equal(t, "access", entries[0].Message)
+		assert.Equal(t, DebugLevel, entries[0].Level)
+		assert.Equal(t, 1, logger.FilterField(StatusKey, http.StatusInternalServerError).Len())
+	})
+
+	t.Run("should disable access log", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+		handler := HTTPMiddleware(logger, HTTPMiddlewareConfig{DisableAccessLog: true})(http.NotFoundHandler())
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Zero(t, logger.Len())
+	})
+
+	t.Run("should flush wrapped writer", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+		handler := HTTPMiddleware(logger, HTTPMiddlewareConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("chunk"))
+			require.NoError(t, http.NewResponseController(w).Flush())
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.True(t, rec.Flushed)
+		assert.Equal(t, 1, logger.FilterField(StatusKey, http.StatusOK).FilterField(BytesKey, int64(5)).Len())
+	})
+
+	t.Run("should hijack wrapped writer", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+		server := httptest.NewServer(HTTPMiddleware(logger, HTTPMiddlewareConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			defer conn.Close()
+			_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+			_ = rw.Flush()
+		})))
+		t.Cleanup(server.Close)
+
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n"))
+		require.NoError(t, err)
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+		assert.Eventually(t, func() bool {
+			return logger.FilterField(StatusKey, http.StatusSwitchingProtocols).Len() == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should report unsupported hijack", func(t *testing.T) {
+		w := &responseWriter{ResponseWriter: httptest.NewRecorder()}
+
+		_, _, err := w.Hijack()
+		assert.ErrorIs(t, err, http.ErrNotSupported)
+		assert.ErrorIs(t, http.NewResponseController(w).SetReadDeadline(time.Now()), http.ErrNotSupported)
+	})
+
+}
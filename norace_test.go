@@ -0,0 +1,5 @@
+//go:build !race
+
+package azalogger
+
+const raceEnabled = false
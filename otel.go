@@ -0,0 +1,326 @@
+package azalogger
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"runtime"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// OtelScopeName is the instrumentation scope of records emitted by NewOtelLogger loggers
+const OtelScopeName = "gitlab.com/ludovic-alarcon/aza-logger"
+
+// Attributes of the caller, from OpenTelemetry semantic conventions
+const (
+	OtelFilePathKey     = "code.file.path"
+	OtelLineNumberKey   = "code.line.number"
+	OtelFunctionNameKey = "code.function.name"
+)
+
+// OtelLoggerProvider is a log.LoggerProvider which can be flushed and shut down, like the SDK one
+type OtelLoggerProvider interface {
+	log.LoggerProvider
+	ForceFlush(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
+type otelLogger struct {
+	logger   log.Logger
+	provider OtelLoggerProvider
+	// ctx carries the span context set by WithContext
+	ctx       context.Context
+	attrs     []log.KeyValue
+	level     *zap.AtomicLevel
+	levels    *levelController
+	caller    bool
+	sampler   *sampler
+	redactor  *redactor
+	extractor *contextExtractor
+	span      *spanRecorder
+}
+
+func (l *otelLogger) Debug(msg string, kv ...any) {
+	l.log(DebugLevel, msg, l.kvToAttrs(DebugLevel, msg, kv))
+}
+
+func (l *otelLogger) Info(msg string, kv ...any) {
+	l.log(InfoLevel, msg, l.kvToAttrs(InfoLevel, msg, kv))
+}
+
+func (l *otelLogger) Warn(msg string, kv ...any) {
+	l.log(WarnLevel, msg, l.kvToAttrs(WarnLevel, msg, kv))
+}
+
+func (l *otelLogger) Error(msg string, kv ...any) {
+	l.log(ErrorLevel, msg, l.kvToAttrs(ErrorLevel, msg, kv))
+}
+
+func (l *otelLogger) Fatal(msg string, kv ...any) {
+	l.log(FatalLevel, msg, l.kvToAttrs(FatalLevel, msg, kv))
+	l.Sync()
+	osExit(1)
+}
+
+// Log with unknown level is logged as info
+func (l *otelLogger) Log(level LogLevel, msg string, fields ...Field) {
+	if !isValidLogLevel(level.String()) {
+		level = InfoLevel
+	}
+
+	fields, _ = l.redactor.fields(fields)
+	l.span.fields(level, msg, fields)
+	attrs := make([]log.KeyValue, 0, len(fields))
+	for _, field := range fields {
+		if field.Type != SkipType {
+			attrs = append(attrs, toOtelKeyValue(field))
+		}
+	}
+	l.log(level, msg, attrs)
+
+	if level == FatalLevel {
+		l.Sync()
+		osExit(1)
+	}
+}
+
+// kvToAttrs redacts kv and records them on the span of WithContext
+func (l *otelLogger) kvToAttrs(level LogLevel, msg string, kv []any) []log.KeyValue {
+	kv = l.redactor.kv(kv)
+	l.span.kv(level, msg, kv)
+	return toOtelKeyValues(kvToFields(kv))
+}
+
+func toOtelKeyValues(fields []Field) []log.KeyValue {
+	attrs := make([]log.KeyValue, 0, len(fields))
+	for _, field := range fields {
+		attrs = append(attrs, toOtelKeyValue(field))
+	}
+	return attrs
+}
+
+// log must be called by exported methods only, the caller being the one calling them
+func (l *otelLogger) log(level LogLevel, msg string, attrs []log.KeyValue) {
+	var pcs [1]uintptr
+	if l.caller {
+		// skip runtime.Callers, log and the exported method of the logger
+		runtime.Callers(3, pcs[:])
+	}
+	l.logAt(pcs[0], level, msg, attrs)
+}
+
+// logCaller logs kv with pc as caller, see callerLogger
+func (l *otelLogger) logCaller(pc uintptr, level LogLevel, msg string, kv []any) {
+	if !l.caller {
+		pc = 0
+	}
+	l.logAt(pc, level, msg, l.kvToAttrs(level, msg, kv))
+}
+
+func (l *otelLogger) logAt(pc uintptr, level LogLevel, msg string, attrs []log.KeyValue) {
+	if !l.level.Enabled(toZapLevel(level)) || !l.sampler.allow(level, msg) {
+		return
+	}
+
+	now := time.Now()
+	if stats, ok := l.sampler.summary(); ok && l.level.Enabled(zapcore.WarnLevel) {
+		summary := l.newRecord(now, WarnLevel, droppedSummaryMessage)
+		summary.AddAttributes(log.Int64("sampled", int64(stats.Sampled)), log.Int64("rate_limited", int64(stats.RateLimited)))
+		l.logger.Emit(l.ctx, summary)
+	}
+
+	r := l.newRecord(now, level, msg)
+	if frame := pcFrame(pc); frame.PC != 0 {
+		r.AddAttributes(log.String(OtelFilePathKey, frame.File), log.Int(OtelLineNumberKey, frame.Line))
+		if frame.Function != "" {
+			r.AddAttributes(log.String(OtelFunctionNameKey, frame.Function))
+		}
+	}
+	r.AddAttributes(attrs...)
+	l.logger.Emit(l.ctx, r)
+}
+
+func (l *otelLogger) newRecord(now time.Time, level LogLevel, msg string) log.Record {
+	var r log.Record
+	r.SetTimestamp(now)
+	r.SetObservedTimestamp(now)
+	r.SetSeverity(otelSeverity(level))
+	r.SetSeverityText(level.String())
+	r.SetBody(log.StringValue(msg))
+	r.AddAttributes(l.attrs...)
+	return r
+}
+
+// Sync exports pending records
+func (l *otelLogger) Sync() {
+	_ = l.provider.ForceFlush(context.Background())
+}
+
+// Close exports pending records and shuts the provider down
+// The logger and loggers derived from it must not be used afterwards
+func (l *otelLogger) Close() error {
+	return l.provider.Shutdown(context.Background())
+}
+
+func (l *otelLogger) With(kv ...any) Logger {
+	child := *l
+	attrs := toOtelKeyValues(kvToFields(l.redactor.kv(kv)))
+	child.attrs = make([]log.KeyValue, 0, len(l.attrs)+len(attrs))
+	child.attrs = append(child.attrs, l.attrs...)
+	child.attrs = append(child.attrs, attrs...)
+	return &child
+}
+
+// WithContext attaches the span context to records natively, instead of trace_id and span_id attributes
+// trace state and baggage members are added as attributes
+func (l *otelLogger) WithContext(ctx context.Context) Logger {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	kv := l.extractor.fields(ctx)
+	if !spanCtx.IsValid() && len(kv) == 0 {
+		return l
+	}
+
+	child := l
+	if len(kv) > 0 {
+		child = l.With(kv...).(*otelLogger)
+	} else {
+		copied := *l
+		child = &copied
+	}
+	if spanCtx.IsValid() {
+		child.ctx = trace.ContextWithSpanContext(context.Background(), spanCtx)
+	}
+	child.span = l.extractor.spanRecorder(ctx)
+	return child
+}
+
+func (l *otelLogger) HTTPLevelHandler(authHandler AuthorizationHandler) http.Handler {
+	return l.levels.handler(authHandler)
+}
+
+func (l *otelLogger) LogLevel() string {
+	return l.level.String()
+}
+
+func (l *otelLogger) Stats() Stats {
+	return l.sampler.Stats()
+}
+
+// NewOtelLogger returns a logger emitting records through the OpenTelemetry Logs API of provider
+// The azaotel package builds one exporting with OTLP/HTTP, Sync and Close flush and shut provider down
+func NewOtelLogger(cfg Config, provider OtelLoggerProvider) Logger {
+	return newOtelLogger(cfg, provider)
+}
+
+func newOtelLogger(cfg Config, provider OtelLoggerProvider) *otelLogger {
+	var zapLevel zapcore.Level
+	if err := zapLevel.UnmarshalText([]byte(getLogLevel(cfg))); err != nil {
+		zapLevel = zapcore.InfoLevel
+	}
+	level := zap.NewAtomicLevelAt(zapLevel)
+
+	extractor := newContextExtractor(cfg, Format{})
+	extractor.native = true
+	return &otelLogger{
+		extractor: extractor,
+		logger:    provider.Logger(OtelScopeName),
+		provider:  provider,
+		ctx:       context.Background(),
+		level:     &level,
+		levels:    newZapLevelController(&level),
+		caller:    !cfg.DisableCaller,
+		sampler:   newSampler(cfg.Sampling),
+		redactor:  newRedactor(cfg.Redaction),
+	}
+}
+
+func otelSeverity(level LogLevel) log.Severity {
+	switch level {
+	case DebugLevel:
+		return log.SeverityDebug
+	case WarnLevel:
+		return log.SeverityWarn
+	case ErrorLevel:
+		return log.SeverityError
+	case FatalLevel:
+		return log.SeverityFatal
+	default:
+		return log.SeverityInfo
+	}
+}
+
+// toOtelKeyValue encodes durations in nanoseconds and times in RFC 3339 like the canonical schema
+func toOtelKeyValue(f Field) log.KeyValue {
+	switch f.Type {
+	case StringType:
+		return log.String(f.Key, f.String)
+	case IntType, Int64Type, DurationType:
+		return log.Int64(f.Key, f.Integer)
+	case BoolType:
+		return log.Bool(f.Key, f.Integer == 1)
+	case Float64Type:
+		return log.Float64(f.Key, math.Float64frombits(uint64(f.Integer)))
+	case TimeType:
+		return log.String(f.Key, f.time().UTC().Format(time.RFC3339Nano))
+	case ObjectType:
+		fields, _ := f.Interface.([]Field)
+		kvs := make([]log.KeyValue, 0, len(fields))
+		for _, field := range fields {
+			if field.Type != SkipType {
+				kvs = append(kvs, toOtelKeyValue(field))
+			}
+		}
+		return log.Map(f.Key, kvs...)
+	default:
+		return log.KeyValue{Key: f.Key, Value: toOtelValue(f.Interface)}
+	}
+}
+
+func toOtelValue(value any) log.Value {
+	switch v := value.(type) {
+	case nil:
+		return log.Value{}
+	case string:
+		return log.StringValue(v)
+	case bool:
+		return log.BoolValue(v)
+	case int:
+		return log.IntValue(v)
+	case int8:
+		return log.Int64Value(int64(v))
+	case int16:
+		return log.Int64Value(int64(v))
+	case int32:
+		return log.Int64Value(int64(v))
+	case int64:
+		return log.Int64Value(v)
+	case uint8:
+		return log.Int64Value(int64(v))
+	case uint16:
+		return log.Int64Value(int64(v))
+	case uint32:
+		return log.Int64Value(int64(v))
+	case float32:
+		return log.Float64Value(float64(v))
+	case float64:
+		return log.Float64Value(v)
+	case []byte:
+		return log.BytesValue(v)
+	case time.Duration:
+		return log.Int64Value(int64(v))
+	case time.Time:
+		return log.StringValue(v.UTC().Format(time.RFC3339Nano))
+	case error:
+		return log.StringValue(v.Error())
+	case fmt.Stringer:
+		return log.StringValue(v.String())
+	default:
+		return log.StringValue(fmt.Sprintf("%+v", v))
+	}
+}
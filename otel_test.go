@@ -0,0 +1,175 @@
+package azalogger
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordExporter keeps exported records in memory
+type recordExporter struct {
+	mu      sync.Mutex
+	exports []sdklog.Record
+}
+
+func (e *recordExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, record := range records {
+		e.exports = append(e.exports, record.Clone())
+	}
+	return nil
+}
+
+func (e *recordExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+func (e *recordExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+func (e *recordExporter) records() []sdklog.Record {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]sdklog.Record(nil), e.exports...)
+}
+
+func recordAttribute(record sdklog.Record, key string) (log.Value, bool) {
+	var value log.Value
+	var found bool
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		if kv.Key == key {
+			value, found = kv.Value, true
+		}
+		return !found
+	})
+	return value, found
+}
+
+func mapValue(kvs []log.KeyValue, key string) log.Value {
+	for _, kv := range kvs {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return log.Value{}
+}
+
+func newTestOtelLogger(t *testing.T, cfg Config) (Logger, *recordExporter) {
+	t.Helper()
+
+	exporter := &recordExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)))
+	return NewOtelLogger(cfg, provider), exporter
+}
+
+func TestOtelLogger(t *testing.T) {
+	t.Run("should export records with severity, body and attributes", func(t *testing.T) {
+		logger, receiver := newTestOtelLogger(t, Config{LogLevel: DebugLevel})
+
+		logger.With("app", "myapp").Debug("debug message", "user", "bob")
+		logger.Warn("warn message", "latency", time.Millisecond)
+		logger.Log(ErrorLevel, "typed message", Int("count", 2), Object("req", String("method", "GET")))
+		logger.Sync()
+
+		records := receiver.records()
+		require.Len(t, records, 3)
+
+		assert.Equal(t, log.SeverityDebug, records[0].Severity())
+		assert.Equal(t, "debug", records[0].SeverityText())
+		assert.Equal(t, "debug message", records[0].Body().AsString())
+		app, _ := recordAttribute(records[0], "app")
+		assert.Equal(t, "myapp", app.AsString())
+		user, _ := recordAttribute(records[0], "user")
+		assert.Equal(t, "bob", user.AsString())
+		file, _ := recordAttribute(records[0], OtelFilePathKey)
+		assert.True(t, strings.HasSuffix(file.AsString(), "otel_test.go"))
+
+		assert.Equal(t, log.SeverityWarn, records[1].Severity())
+		latency, _ := recordAttribute(records[1], "latency")
+		assert.Equal(t, int64(time.Millisecond), latency.AsInt64())
+
+		assert.Equal(t, log.SeverityError, records[2].Severity())
+		count, _ := recordAttribute(records[2], "count")
+		assert.Equal(t, int64(2), count.AsInt64())
+		req, _ := recordAttribute(records[2], "req")
+		assert.Equal(t, "GET", mapValue(req.AsMap(), "method").AsString())
+	})
+
+	t.Run("should attach trace context natively", func(t *testing.T) {
+		logger, receiver := newTestOtelLogger(t, Config{})
+		ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{2},
+			TraceFlags: trace.FlagsSampled,
+		}))
+
+		logger.WithContext(ctx).Info("context message")
+		logger.Sync()
+
+		records := receiver.records()
+		require.Len(t, records, 1)
+		assert.Equal(t, trace.TraceID{1}, records[0].TraceID())
+		assert.Equal(t, trace.SpanID{2}, records[0].SpanID())
+		assert.Equal(t, trace.FlagsSampled, records[0].TraceFlags())
+		_, ok := recordAttribute(records[0], TraceIDKey)
+		assert.False(t, ok)
+	})
+
+	t.Run("should filter by level and redact values", func(t *testing.T) {
+		logger, receiver := newTestOtelLogger(t, Config{
+			LogLevel:      WarnLevel,
+			DisableCaller: true,
+			Redaction:     &RedactionConfig{Keys: []string{"password"}},
+		})
+
+		logger.Info("ignored")
+		logger.Warn("login", "password", "secret")
+		logger.Sync()
+
+		records := receiver.records()
+		require.Len(t, records, 1)
+		password, _ := recordAttribute(records[0], "password")
+		assert.Equal(t, defaultRedactionMask, password.AsString())
+		_, ok := recordAttribute(records[0], OtelFilePathKey)
+		assert.False(t, ok)
+		assert.Equal(t, WarnLevel.String(), logger.LogLevel())
+	})
+
+	t.Run("should export pending records on close", func(t *testing.T) {
+		logger, receiver := newTestOtelLogger(t, Config{})
+
+		logger.Info("pending")
+		closer, ok := logger.(io.Closer)
+		require.True(t, ok)
+		require.NoError(t, closer.Close())
+
+		assert.Len(t, receiver.records(), 1)
+		assert.NoError(t, closer.Close())
+	})
+
+	t.Run("should exit on fatal after exporting", func(t *testing.T) {
+		var code int
+		osExit = func(c int) { code = c }
+		t.Cleanup(func() { osExit = os.Exit })
+		logger, receiver := newTestOtelLogger(t, Config{})
+
+		logger.Fatal("fatal message")
+
+		assert.Equal(t, 1, code)
+		records := receiver.records()
+		require.Len(t, records, 1)
+		assert.Equal(t, log.SeverityFatal, records[0].Severity())
+	})
+}
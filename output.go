@@ -0,0 +1,124 @@
+package azalogger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	StdoutOutput = "stdout"
+	StderrOutput = "stderr"
+)
+
+// openOutputs returns a writer over cfg Outputs and Writers, stderr when none of them is set
+// The writer is asynchronous when cfg.Async is set, cfg.Format is validated before opening outputs
+// The closer stops the async goroutine and closes files opened from cfg.Outputs, cfg.Writers are left open
+func openOutputs(cfg Config) (io.Writer, *outputCloser, error) {
+	if err := cfg.format().validate(); err != nil {
+		return nil, nil, err
+	}
+	out, closer, err := openSyncOutputs(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Async == nil {
+		return out, closer, nil
+	}
+
+	async := newAsyncWriter(out, *cfg.Async)
+	// queued entries are written before closing files
+	closer.closers = append([]io.Closer{async}, closer.closers...)
+	return async, closer, nil
+}
+
+func openSyncOutputs(cfg Config) (io.Writer, *outputCloser, error) {
+	closer := &outputCloser{}
+	writers := make([]io.Writer, 0, len(cfg.Outputs)+len(cfg.Writers))
+	for _, output := range cfg.Outputs {
+		w, err := openOutput(output, cfg.Rotation)
+		if err != nil {
+			_ = closer.Close()
+			return nil, nil, err
+		}
+		if c, ok := w.(io.Closer); ok && w != os.Stdout && w != os.Stderr {
+			closer.closers = append(closer.closers, c)
+		}
+		writers = append(writers, w)
+	}
+	for _, w := range cfg.Writers {
+		if w != nil {
+			writers = append(writers, w)
+		}
+	}
+
+	switch len(writers) {
+	case 0:
+		return os.Stderr, closer, nil
+	case 1:
+		return writers[0], closer, nil
+	default:
+		return multiWriter(writers), closer, nil
+	}
+}
+
+func openOutput(output string, rotation *Rotation) (io.Writer, error) {
+	switch output {
+	case StdoutOutput:
+		return os.Stdout, nil
+	case StderrOutput:
+		return os.Stderr, nil
+	default:
+		if rotation != nil {
+			return NewRotatingFile(output, *rotation)
+		}
+
+		file, err := os.OpenFile(filepath.Clean(output), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open log output %q: %w", output, err)
+		}
+		return file, nil
+	}
+}
+
+// outputCloser closes resources of a logger once, it's shared with derived loggers
+type outputCloser struct {
+	once    sync.Once
+	closers []io.Closer
+	err     error
+}
+
+func (c *outputCloser) Close() error {
+	c.once.Do(func() {
+		for _, closer := range c.closers {
+			c.err = errors.Join(c.err, closer.Close())
+		}
+	})
+	return c.err
+}
+
+// multiWriter writes to every writer even when one of them fails
+type multiWriter []io.Writer
+
+func (m multiWriter) Write(p []byte) (int, error) {
+	var errs error
+	for _, w := range m {
+		if _, err := w.Write(p); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	return len(p), errs
+}
+
+func (m multiWriter) Sync() error {
+	var errs error
+	for _, w := range m {
+		if syncer, ok := w.(interface{ Sync() error }); ok {
+			errs = errors.Join(errs, syncer.Sync())
+		}
+	}
+	return errs
+}
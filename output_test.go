@@ -0,0 +1,148 @@
+package azalogger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closeTrackingWriter struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (w *closeTrackingWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestOutputs(t *testing.T) {
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			t.Run("should write to file and writers", func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "app.log")
+				var buff bytes.Buffer
+
+				logger, err := NewLogger(Config{
+					Backend: backend,
+					Outputs: []string{path},
+					Writers: []io.Writer{&buff, failingWriter{}},
+				})
+				require.NoError(t, err)
+
+				logger.Info("to every sink", "app", "myapp")
+				logger.Sync()
+
+				content, err := os.ReadFile(path)
+				require.NoError(t, err)
+				assert.Contains(t, string(content), "to every sink")
+				assert.Contains(t, buff.String(), "to every sink")
+				assert.Equal(t, string(content), buff.String())
+			})
+
+			t.Run("should append to existing file", func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "app.log")
+				require.NoError(t, os.WriteFile(path, []byte("existing\n"), 0o600))
+
+				logger, err := NewLogger(Config{Backend: backend, Outputs: []string{path}})
+				require.NoError(t, err)
+				logger.Info("appended")
+
+				content, err := os.ReadFile(path)
+				require.NoError(t, err)
+				assert.Contains(t, string(content), "existing\n")
+				assert.Contains(t, string(content), "appended")
+			})
+
+			t.Run("should return an error when output cannot be opened", func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "missing", "app.log")
+
+				logger, err := NewLogger(Config{
+					Backend: backend,
+					Outputs: []string{StdoutOutput, path},
+				})
+				require.Error(t, err)
+				assert.ErrorContains(t, err, path)
+				assert.Nil(t, logger)
+			})
+
+			t.Run("should close opened files only", func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "app.log")
+				writer := &closeTrackingWriter{}
+
+				logger, err := NewLogger(Config{
+					Backend: backend,
+					Outputs: []string{StdoutOutput, path},
+					Writers: []io.Writer{writer},
+					Async:   &AsyncConfig{},
+				})
+				require.NoError(t, err)
+				logger.Info("before close")
+
+				closer, ok := logger.With("app", "myapp").(io.Closer)
+				require.True(t, ok)
+				require.NoError(t, closer.Close())
+				require.NoError(t, closer.Close())
+
+				assert.Contains(t, readFile(t, path), "before close")
+				assert.Contains(t, writer.String(), "before close")
+				assert.False(t, writer.closed)
+
+				var closers []io.Closer
+				switch l := logger.(type) {
+				case *zapLogger:
+					closers = l.closer.closers
+				case *slogLogger:
+					closers = l.closer.closers
+				}
+				require.Len(t, closers, 2)
+				_, err = closers[1].(*os.File).Write([]byte("after close"))
+				assert.ErrorIs(t, err, os.ErrClosed)
+				_, err = os.Stdout.Stat()
+				assert.NoError(t, err)
+			})
+		})
+	}
+
+	t.Run("should write to stderr", func(t *testing.T) {
+		saveStdErr := os.Stderr
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		os.Stderr = w
+
+		defer func() {
+			os.Stderr = saveStdErr
+			_ = w.Close()
+			_ = r.Close()
+		}()
+
+		logger, err := NewLogger(Config{Backend: SlogBackend, Outputs: []string{StderrOutput}})
+		require.NoError(t, err)
+		logger.Info("to stderr")
+
+		_ = w.Close()
+		os.Stderr = saveStdErr
+
+		var buff bytes.Buffer
+		_, err = io.Copy(&buff, r)
+		require.NoError(t, err)
+		assert.Contains(t, buff.String(), "to stderr")
+	})
+
+	t.Run("should default to stderr for every backend when nothing is set", func(t *testing.T) {
+		out, _, err := openOutputs(Config{})
+		require.NoError(t, err)
+		assert.Same(t, os.Stderr, out)
+	})
+}
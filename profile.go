@@ -0,0 +1,118 @@
+package azalogger
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Profile shapes entries for a log ingestion platform
+type Profile string
+
+const (
+	// DefaultProfile emits the canonical schema configured by Format
+	DefaultProfile Profile = ""
+	// GCPProfile emits Google Cloud Logging structured entries
+	GCPProfile Profile = "gcp"
+	// ECSProfile emits Elastic Common Schema entries
+	ECSProfile Profile = "ecs"
+)
+
+// format returns the Format used by backends, cfg.Format keys being
+// replaced by the ones expected by the profile
+func (cfg Config) format() Format {
+	format := cfg.Format
+	switch cfg.Profile {
+	case GCPProfile:
+		format = Format{
+			Encoding:   JSONEncoding,
+			TimeFormat: time.RFC3339Nano,
+			TimeKey:    GCPTimeKey,
+			LevelKey:   GCPSeverityKey,
+			MessageKey: GCPMessageKey,
+		}
+		format.gcpProjectID = cfg.GCPProjectID
+		if format.gcpProjectID == "" {
+			format.gcpProjectID = os.Getenv(GCPProjectEnvVar)
+		}
+	case ECSProfile:
+		format = Format{
+			Encoding:   JSONEncoding,
+			TimeFormat: ECSTimeFormat,
+			TimeKey:    ECSTimestampKey,
+			LevelKey:   ECSLevelKey,
+			MessageKey: ECSMessageKey,
+		}
+		format.serviceName = cfg.ServiceName
+	}
+	format.profile = cfg.Profile
+	return format.withDefaults(cfg.Env)
+}
+
+func (p Profile) isValid() bool {
+	switch p {
+	case DefaultProfile, GCPProfile, ECSProfile:
+		return true
+	default:
+		return false
+	}
+}
+
+// traceFields returns span context IDs with the keys of the profile
+func (f Format) traceFields(spanCtx trace.SpanContext) []any {
+	switch f.profile {
+	case GCPProfile:
+		return gcpTraceFields(spanCtx, f.gcpProjectID)
+	case ECSProfile:
+		return ecsTraceFields(spanCtx)
+	default:
+		return traceFields(spanCtx)
+	}
+}
+
+// staticFields are added to every entry
+func (f Format) staticFields() []Field {
+	if f.profile == ECSProfile {
+		return ecsStaticFields(f.serviceName)
+	}
+	return nil
+}
+
+func (f Format) callerKey() string {
+	switch f.profile {
+	case GCPProfile:
+		return GCPSourceLocationKey
+	case ECSProfile:
+		return ECSOriginKey
+	default:
+		return CallerKey
+	}
+}
+
+func (f Format) stacktraceKey() string {
+	if f.profile == ECSProfile {
+		return ECSStacktraceKey
+	}
+	return StacktraceKey
+}
+
+// sourceFields returns the caller nested under callerKey by profiles
+func (f Format) sourceFields(file string, line int, function string) []Field {
+	file = trimFile(file)
+	if f.profile == ECSProfile {
+		return ecsSourceFields(file, line, function)
+	}
+	return gcpSourceFields(file, line, function)
+}
+
+// trimFile keeps the file and its directory like the canonical caller
+func trimFile(file string) string {
+	if idx := strings.LastIndexByte(file, '/'); idx >= 0 {
+		if idx = strings.LastIndexByte(file[:idx], '/'); idx >= 0 {
+			return file[idx+1:]
+		}
+	}
+	return file
+}
@@ -0,0 +1,6 @@
+//go:build race
+
+package azalogger
+
+// raceEnabled skips allocation checks, the race detector randomly drops sync.Pool items
+const raceEnabled = true
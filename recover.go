@@ -0,0 +1,103 @@
+package azalogger
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+const (
+	DefaultPanicMessage = "panic recovered"
+
+	PanicKey = "panic"
+)
+
+// RecoverAndLog logs a panic at error level and stops it, it must be deferred directly
+//
+//	go func() {
+//		defer azalogger.RecoverAndLog(log)
+//		work()
+//	}()
+func RecoverAndLog(logger Logger) {
+	if value := recover(); value != nil {
+		logPanic(logger, value)
+	}
+}
+
+// RecoveryMiddleware logs panics of handlers at error level and replies 500 when nothing has been written
+// Panics are logged with the logger of the request context, stored by HTTPMiddleware, or with logger
+// http.ErrAbortHandler is not logged and keeps aborting the request
+func RecoveryMiddleware(logger Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw, ok := w.(*responseWriter)
+			if !ok {
+				rw = &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			}
+
+			defer func() {
+				value := recover()
+				if value == nil {
+					return
+				}
+				if err, ok := value.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(value)
+				}
+
+				requestLogger, ok := LoggerFromContext(r.Context())
+				if !ok {
+					requestLogger = logger
+				}
+				logPanic(requestLogger.WithContext(r.Context()), value)
+
+				if !rw.wroteHeader {
+					http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// stackLogger is implemented by backends logging a given stack instead of capturing their own
+type stackLogger interface {
+	logStack(level LogLevel, msg, stack string, fields []Field)
+}
+
+// logPanic logs the stack of the panic as the entry stacktrace, the backend one pointing into the recovery
+func logPanic(logger Logger, value any) {
+	fields := []Field{String(PanicKey, fmt.Sprint(value))}
+	if err, ok := value.(error); ok {
+		fields = append(fields, Err(err))
+	}
+
+	stack := panicStack()
+	if l, ok := logger.(stackLogger); ok {
+		l.logStack(ErrorLevel, DefaultPanicMessage, stack, fields)
+		return
+	}
+	logger.Log(ErrorLevel, DefaultPanicMessage, append(fields, String(StacktraceKey, stack))...)
+}
+
+// panicStack returns the stack of the panicking goroutine formatted like stacktrace
+// It starts at the function which panicked, without the recovering frames
+func panicStack() string {
+	frames := runtime.CallersFrames(callers(1))
+	for frame, more := frames.Next(); more; frame, more = frames.Next() {
+		if frame.Function != "runtime.gopanic" {
+			continue
+		}
+		// runtime errors go through runtime.panicmem, runtime.sigpanic...
+		var b strings.Builder
+		for frame, more = frames.Next(); more; frame, more = frames.Next() {
+			if b.Len() > 0 || !strings.HasPrefix(frame.Function, "runtime.") {
+				writeFrame(&b, frame)
+			}
+		}
+		return b.String()
+	}
+	return stacktrace(0)
+}
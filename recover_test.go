@@ -0,0 +1,155 @@
+package azalogger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRecoverAndLog(t *testing.T) {
+	t.Run("should log panic and stop it", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			defer RecoverAndLog(logger)
+			panic(errors.New("boom"))
+		}()
+		<-done
+
+		entries := logger.All()
+		require.Len(t, entries, 1)
+		assert.Equal(t, ErrorLevel, entries[0].Level)
+		assert.Equal(t, DefaultPanicMessage, entries[0].Message)
+		value, _ := entries[0].Field(PanicKey)
+		assert.Equal(t, "boom", value.Value())
+		err, ok := entries[0].Field(ErrorKey)
+		require.True(t, ok)
+		assert.EqualError(t, err.Value().(error), "boom")
+
+		stack, _ := entries[0].Field(StacktraceKey)
+		assert.Regexp(t, `^gitlab.com/ludovic-alarcon/aza-logger\.TestRecoverAndLog\.func1\.1\n\t\S+/recover_test\.go:\d+$`, stack.Value())
+		assert.NotContains(t, stack.Value(), "runtime.gopanic")
+		assert.NotContains(t, stack.Value(), "RecoverAndLog\n")
+	})
+
+	t.Run("should start stack at runtime error", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			defer RecoverAndLog(logger)
+			var m map[string]int
+			m["boom"] = 1
+		}()
+		<-done
+
+		stack, _ := logger.All()[0].Field(StacktraceKey)
+		assert.Regexp(t, `^gitlab.com/ludovic-alarcon/aza-logger\.TestRecoverAndLog\.func2\.1\n\t\S+/recover_test\.go:\d+$`, stack.Value())
+	})
+
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	for name, backend := range backends {
+		t.Run("should log panic stack as the only stacktrace with "+name, func(t *testing.T) {
+			logger, buff := newBufferedLogger(t, Config{Backend: backend, Format: Format{Encoding: JSONEncoding}})
+
+			func() {
+				defer RecoverAndLog(logger)
+				panic("boom")
+			}()
+
+			assert.Equal(t, 1, strings.Count(buff.String(), `"`+StacktraceKey+`"`))
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(buff.Bytes(), &entry))
+			assert.Equal(t, "boom", entry[PanicKey])
+			assert.True(t, strings.HasPrefix(entry[StacktraceKey].(string), "gitlab.com/ludovic-alarcon/aza-logger.TestRecoverAndLog.func"))
+			assert.NotContains(t, entry[StacktraceKey], "RecoverAndLog\n")
+		})
+	}
+
+	t.Run("should do nothing without panic", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+
+		func() {
+			defer RecoverAndLog(logger)
+		}()
+
+		assert.Equal(t, 0, logger.Len())
+	})
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	t.Run("should log panic with request logger and reply 500", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+		handler := HTTPMiddleware(logger, HTTPMiddlewareConfig{})(RecoveryMiddleware(logger)(panicking))
+
+		spanCtx := trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}})
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req = req.WithContext(trace.ContextWithSpanContext(req.Context(), spanCtx))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		entries := logger.FilterField(PathKey, "/users")
+		require.Equal(t, 2, entries.Len())
+
+		assert.Equal(t, DefaultPanicMessage, entries[0].Message)
+		assert.Equal(t, ErrorLevel, entries[0].Level)
+		assert.Equal(t, spanCtx.TraceID().String(), entries[0].TraceID)
+		value, _ := entries[0].Field(PanicKey)
+		assert.Equal(t, "boom", value.Value())
+		_, ok := entries[0].Field(StacktraceKey)
+		assert.True(t, ok)
+
+		status, _ := entries[1].Field(StatusKey)
+		assert.Equal(t, http.StatusInternalServerError, status.Value())
+	})
+
+	t.Run("should fallback to given logger", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+		rec := httptest.NewRecorder()
+
+		RecoveryMiddleware(logger)(panicking).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, 1, logger.FilterMessage(DefaultPanicMessage).Len())
+	})
+
+	t.Run("should keep status already written", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+		rec := httptest.NewRecorder()
+
+		RecoveryMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			panic("boom")
+		})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, 1, logger.Len())
+	})
+
+	t.Run("should propagate abort handler", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{})
+		handler := RecoveryMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+		assert.Equal(t, 0, logger.Len())
+	})
+}
@@ -0,0 +1,191 @@
+package azalogger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const defaultRedactionMask = "[REDACTED]"
+
+// Common patterns to be used in RedactionConfig.Patterns
+var (
+	JWTPattern        = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	EmailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	CreditCardPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+)
+
+// Redactable is implemented by types knowing how to redact themselves
+// It's honoured by every backend, even without RedactionConfig
+type Redactable interface {
+	Redact() any
+}
+
+// RedactionConfig masks values of key/value pairs and fields
+type RedactionConfig struct {
+	// Keys whose values are masked, case insensitive
+	// They also match a segment of dotted keys, like slog groups flattened by the bridge
+	Keys []string
+	// Patterns matched in string and error values are masked
+	Patterns []*regexp.Regexp
+	// Mask defaults to "[REDACTED]"
+	Mask string
+}
+
+// redactor is nil safe, a nil redactor leaves values untouched
+type redactor struct {
+	keys     map[string]struct{}
+	patterns []*regexp.Regexp
+	mask     string
+}
+
+func newRedactor(cfg *RedactionConfig) *redactor {
+	r := &redactor{mask: defaultRedactionMask}
+	if cfg == nil {
+		return r
+	}
+
+	if cfg.Mask != "" {
+		r.mask = cfg.Mask
+	}
+	r.patterns = cfg.Patterns
+	r.keys = make(map[string]struct{}, len(cfg.Keys))
+	for _, key := range cfg.Keys {
+		r.keys[strings.ToLower(key)] = struct{}{}
+	}
+	return r
+}
+
+// kv returns kv with redacted values, it's copied only when a value is redacted
+func (r *redactor) kv(kv []any) []any {
+	if r == nil {
+		return kv
+	}
+
+	redacted, copied := kv, false
+	for i := 0; i+1 < len(kv); i += 2 {
+		value, ok := r.value(kvKey(kv[i]), kv[i+1])
+		if !ok {
+			continue
+		}
+		if !copied {
+			redacted, copied = append([]any(nil), kv...), true
+		}
+		redacted[i+1] = value
+	}
+	return redacted
+}
+
+// fields returns fields with redacted values and true when one of them has been redacted
+// fields are copied only when a value is redacted
+func (r *redactor) fields(fields []Field) ([]Field, bool) {
+	if r == nil {
+		return fields, false
+	}
+
+	redacted, copied := fields, false
+	for i, field := range fields {
+		field, ok := r.field(field)
+		if !ok {
+			continue
+		}
+		if !copied {
+			redacted, copied = append([]Field(nil), fields...), true
+		}
+		redacted[i] = field
+	}
+	return redacted, copied
+}
+
+func (r *redactor) field(field Field) (Field, bool) {
+	if r == nil {
+		return field, false
+	}
+
+	switch field.Type {
+	case SkipType:
+		return field, false
+	case ObjectType:
+		if r.isSensitiveKey(field.Key) {
+			return String(field.Key, r.mask), true
+		}
+		fields, _ := field.Interface.([]Field)
+		redacted, ok := r.fields(fields)
+		if !ok {
+			return field, false
+		}
+		return Object(field.Key, redacted...), true
+	case StringType:
+		if r.isSensitiveKey(field.Key) {
+			return String(field.Key, r.mask), true
+		}
+		if redacted := r.redactString(field.String); redacted != field.String {
+			return String(field.Key, redacted), true
+		}
+		return field, false
+	case ErrorType, AnyType:
+		value, ok := r.value(field.Key, field.Interface)
+		if !ok {
+			return field, false
+		}
+		return Any(field.Key, value), true
+	default:
+		if r.isSensitiveKey(field.Key) {
+			return String(field.Key, r.mask), true
+		}
+		return field, false
+	}
+}
+
+func kvKey(key any) string {
+	if s, ok := key.(string); ok {
+		return s
+	}
+	return fmt.Sprint(key)
+}
+
+// value returns the redacted value and true when value has been redacted
+func (r *redactor) value(key string, value any) (any, bool) {
+	if r.isSensitiveKey(key) {
+		return r.mask, true
+	}
+
+	switch v := value.(type) {
+	case Redactable:
+		return v.Redact(), true
+	case string:
+		if redacted := r.redactString(v); redacted != v {
+			return redacted, true
+		}
+	case error:
+		msg := v.Error()
+		if redacted := r.redactString(msg); redacted != msg {
+			return redacted, true
+		}
+	}
+	return value, false
+}
+
+func (r *redactor) isSensitiveKey(key string) bool {
+	if len(r.keys) == 0 {
+		return false
+	}
+	key = strings.ToLower(key)
+	if _, ok := r.keys[key]; ok || !strings.Contains(key, ".") {
+		return ok
+	}
+	// a sensitive group, like credentials in credentials.secret, masks every key of the group
+	for segment := range strings.SplitSeq(key, ".") {
+		if _, ok := r.keys[segment]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) redactString(s string) string {
+	for _, pattern := range r.patterns {
+		s = pattern.ReplaceAllString(s, r.mask)
+	}
+	return s
+}
@@ -0,0 +1,391 @@
+package azalogger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+)
+
+// Rotation configures rotation of file outputs
+// Size and time based rotation can be combined, the first reached triggers it
+type Rotation struct {
+	// MaxSize in bytes of the file before rotating, 0 disables size based rotation
+	MaxSize int64
+	// Interval between rotations, 0 disables time based rotation
+	Interval time.Duration
+	// MaxBackups is the number of rotated files to keep, 0 keeps them all
+	MaxBackups int
+	// MaxAge removes rotated files older than it, 0 keeps them all
+	MaxAge time.Duration
+	// Compress rotated files with gzip
+	Compress bool
+	// ReopenOnSIGHUP reopens the file on SIGHUP, for logrotate compatibility
+	ReopenOnSIGHUP bool
+}
+
+// RotatingFile is an io.Writer over a file rotated according to Rotation
+// Rotated files are renamed <name>-<timestamp><ext> next to the file, or <name>-<timestamp>-<n><ext>
+// when rotated more than once in the same millisecond. They are compressed in the background.
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	rotation Rotation
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	now      func() time.Time
+	signals  chan os.Signal
+	done     chan struct{}
+	closed   bool
+	// lastBackup is the timestamp of the last backup and lastIndex its counter
+	lastBackup string
+	lastIndex  int
+	// compressing serializes background compression and retention of rotated files
+	compressing sync.Mutex
+	pending     sync.WaitGroup
+}
+
+func NewRotatingFile(path string, rotation Rotation) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path:     filepath.Clean(path),
+		rotation: rotation,
+		now:      time.Now,
+		done:     make(chan struct{}),
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	if rotation.ReopenOnSIGHUP {
+		f.signals = make(chan os.Signal, 1)
+		signal.Notify(f.signals, syscall.SIGHUP)
+		go f.reopenOn(f.signals)
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	// a previous rotation may have failed to reopen the file
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *RotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed || f.file == nil {
+		return os.ErrClosed
+	}
+	return f.file.Sync()
+}
+
+// Close stops listening to SIGHUP, waits for pending compressions and closes the file
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.pending.Wait()
+
+	f.closed = true
+	if f.signals != nil {
+		signal.Stop(f.signals)
+	}
+	close(f.done)
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Rotate rotates the file regardless of Rotation thresholds
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// Reopen closes and reopens the file at the same path
+// to be used when an external tool like logrotate moved it
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+	return f.open()
+}
+
+func (f *RotatingFile) reopenOn(signals <-chan os.Signal) {
+	for {
+		select {
+		case <-signals:
+			if err := f.Reopen(); err != nil && !errors.Is(err, os.ErrClosed) {
+				fmt.Fprintf(os.Stderr, "unable to reopen log file %q: %v\n", f.path, err)
+			}
+		case <-f.done:
+			return
+		}
+	}
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to open log file %q: %w", f.path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("unable to stat log file %q: %w", f.path, err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+	return nil
+}
+
+func (f *RotatingFile) shouldRotate(writeSize int64) bool {
+	if f.rotation.MaxSize > 0 && f.size > 0 && f.size+writeSize > f.rotation.MaxSize {
+		return true
+	}
+	return f.rotation.Interval > 0 && f.now().Sub(f.openedAt) >= f.rotation.Interval
+}
+
+func (f *RotatingFile) rotate() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+
+	backup := f.backupName(f.now())
+	renameErr := os.Rename(f.path, backup)
+	if renameErr != nil && !errors.Is(renameErr, os.ErrNotExist) {
+		return fmt.Errorf("unable to rotate log file %q: %w", f.path, renameErr)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	now := f.now()
+	if f.rotation.Compress && renameErr == nil {
+		f.pending.Add(1)
+		go f.compress(backup, now)
+		return nil
+	}
+	return f.cleanup(now)
+}
+
+// compress gzips backup in the background not to block writers, then removes expired backups
+func (f *RotatingFile) compress(backup string, now time.Time) {
+	defer f.pending.Done()
+	f.compressing.Lock()
+	defer f.compressing.Unlock()
+
+	// backup may have been removed by the retention of a previous compression
+	if err := compressFile(backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if err := f.cleanup(now); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to remove rotated log files of %q: %v\n", f.path, err)
+	}
+}
+
+// backupName returns a name not used by another backup, compressed or not,
+// a counter is added when rotating more than once in the same millisecond
+func (f *RotatingFile) backupName(t time.Time) string {
+	dir, name := filepath.Split(f.path)
+	ext := filepath.Ext(name)
+	prefix := strings.TrimSuffix(name, ext)
+	timestamp := t.UTC().Format(backupTimeFormat)
+
+	// the counter keeps increasing when retention removed previous backups, so they stay ordered
+	index := 0
+	if timestamp == f.lastBackup {
+		index = f.lastIndex + 1
+	}
+	for {
+		backup := filepath.Join(dir, fmt.Sprintf("%s-%s%s", prefix, timestamp, ext))
+		if index > 0 {
+			backup = filepath.Join(dir, fmt.Sprintf("%s-%s-%d%s", prefix, timestamp, index, ext))
+		}
+		if !fileExists(backup) && !fileExists(backup+compressSuffix) {
+			f.lastBackup, f.lastIndex = timestamp, index
+			return backup
+		}
+		index++
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+type backupFile struct {
+	path      string
+	timestamp time.Time
+	// index is the counter of backups rotated in the same millisecond
+	index int
+}
+
+// parseBackupTimestamp parses <timestamp> and <timestamp>-<index>
+func parseBackupTimestamp(value string) (time.Time, int, bool) {
+	if t, err := time.Parse(backupTimeFormat, value); err == nil {
+		return t, 0, true
+	}
+
+	i := strings.LastIndex(value, "-")
+	if i < 0 {
+		return time.Time{}, 0, false
+	}
+	index, err := strconv.Atoi(value[i+1:])
+	if err != nil || index <= 0 {
+		return time.Time{}, 0, false
+	}
+	t, err := time.Parse(backupTimeFormat, value[:i])
+	return t, index, err == nil
+}
+
+// backups returns rotated files sorted from newest to oldest
+func (f *RotatingFile) backups() ([]backupFile, error) {
+	dir, name := filepath.Split(f.path)
+	if dir == "" {
+		dir = "."
+	}
+	ext := filepath.Ext(name)
+	prefix := strings.TrimSuffix(name, ext) + "-"
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]backupFile, 0, len(dirEntries))
+	for _, entry := range dirEntries {
+		if entry.IsDir() {
+			continue
+		}
+		timestamp, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok {
+			continue
+		}
+		timestamp = strings.TrimSuffix(strings.TrimSuffix(timestamp, compressSuffix), ext)
+		t, index, ok := parseBackupTimestamp(timestamp)
+		if !ok {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, entry.Name()), timestamp: t, index: index})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].timestamp.Equal(backups[j].timestamp) {
+			return backups[i].index > backups[j].index
+		}
+		return backups[i].timestamp.After(backups[j].timestamp)
+	})
+	return backups, nil
+}
+
+func (f *RotatingFile) cleanup(now time.Time) error {
+	if f.rotation.MaxBackups <= 0 && f.rotation.MaxAge <= 0 {
+		return nil
+	}
+
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	cutoff := now.Add(-f.rotation.MaxAge)
+	for i, backup := range backups {
+		tooMany := f.rotation.MaxBackups > 0 && i >= f.rotation.MaxBackups
+		tooOld := f.rotation.MaxAge > 0 && backup.timestamp.Before(cutoff)
+		if tooMany || tooOld {
+			errs = errors.Join(errs, os.Remove(backup.path))
+		}
+	}
+	return errs
+}
+
+func compressFile(path string) (err error) {
+	src, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.OpenFile(filepath.Clean(path+compressSuffix), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dst.Name())
+		return fmt.Errorf("unable to compress log file %q: %w", path, err)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,254 @@
+package azalogger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Add(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestRotatingFile(t *testing.T, rotation Rotation) (*RotatingFile, *fakeClock) {
+	t.Helper()
+
+	clock := &fakeClock{now: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+	f, err := NewRotatingFile(filepath.Join(t.TempDir(), "app.log"), rotation)
+	require.NoError(t, err)
+	f.now = clock.Now
+	f.openedAt = clock.Now()
+	t.Cleanup(func() { _ = f.Close() })
+
+	return f, clock
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestRotatingFile(t *testing.T) {
+	t.Run("should rotate on size", func(t *testing.T) {
+		f, clock := newTestRotatingFile(t, Rotation{MaxSize: 10})
+		dir := filepath.Dir(f.path)
+
+		_, err := f.Write([]byte("12345678\n"))
+		require.NoError(t, err)
+		clock.Add(time.Second)
+		_, err = f.Write([]byte("abcdefgh\n"))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"app-2025-01-02T03-04-06.000.log", "app.log"}, listDir(t, dir))
+		assert.Equal(t, "12345678\n", readFile(t, filepath.Join(dir, "app-2025-01-02T03-04-06.000.log")))
+		assert.Equal(t, "abcdefgh\n", readFile(t, f.path))
+	})
+
+	t.Run("should not rotate an empty file on size", func(t *testing.T) {
+		f, _ := newTestRotatingFile(t, Rotation{MaxSize: 1})
+
+		_, err := f.Write([]byte("bigger than max size\n"))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"app.log"}, listDir(t, filepath.Dir(f.path)))
+	})
+
+	t.Run("should rotate on interval", func(t *testing.T) {
+		f, clock := newTestRotatingFile(t, Rotation{Interval: time.Hour})
+		dir := filepath.Dir(f.path)
+
+		_, err := f.Write([]byte("first\n"))
+		require.NoError(t, err)
+		clock.Add(59 * time.Minute)
+		_, err = f.Write([]byte("second\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app.log"}, listDir(t, dir))
+
+		clock.Add(time.Minute)
+		_, err = f.Write([]byte("third\n"))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"app-2025-01-02T04-04-05.000.log", "app.log"}, listDir(t, dir))
+		assert.Equal(t, "first\nsecond\n", readFile(t, filepath.Join(dir, "app-2025-01-02T04-04-05.000.log")))
+		assert.Equal(t, "third\n", readFile(t, f.path))
+	})
+
+	t.Run("should keep max backups", func(t *testing.T) {
+		f, clock := newTestRotatingFile(t, Rotation{MaxBackups: 2})
+		dir := filepath.Dir(f.path)
+
+		for range 4 {
+			clock.Add(time.Second)
+			require.NoError(t, f.Rotate())
+		}
+
+		assert.Equal(t, []string{
+			"app-2025-01-02T03-04-08.000.log",
+			"app-2025-01-02T03-04-09.000.log",
+			"app.log",
+		}, listDir(t, dir))
+	})
+
+	t.Run("should remove backups older than max age", func(t *testing.T) {
+		f, clock := newTestRotatingFile(t, Rotation{MaxAge: 24 * time.Hour})
+		dir := filepath.Dir(f.path)
+
+		require.NoError(t, f.Rotate())
+		clock.Add(12 * time.Hour)
+		require.NoError(t, f.Rotate())
+		clock.Add(13 * time.Hour)
+		require.NoError(t, f.Rotate())
+
+		assert.Equal(t, []string{
+			"app-2025-01-02T15-04-05.000.log",
+			"app-2025-01-03T04-04-05.000.log",
+			"app.log",
+		}, listDir(t, dir))
+	})
+
+	t.Run("should compress rotated files", func(t *testing.T) {
+		f, _ := newTestRotatingFile(t, Rotation{Compress: true, MaxBackups: 1})
+		dir := filepath.Dir(f.path)
+
+		_, err := f.Write([]byte("compressed\n"))
+		require.NoError(t, err)
+		require.NoError(t, f.Rotate())
+		f.pending.Wait()
+
+		assert.Equal(t, []string{"app-2025-01-02T03-04-05.000.log.gz", "app.log"}, listDir(t, dir))
+
+		file, err := os.Open(filepath.Join(dir, "app-2025-01-02T03-04-05.000.log.gz"))
+		require.NoError(t, err)
+		defer func() { _ = file.Close() }()
+		gz, err := gzip.NewReader(file)
+		require.NoError(t, err)
+		content, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		assert.Equal(t, "compressed\n", string(content))
+	})
+
+	t.Run("should not overwrite backups rotated in the same millisecond", func(t *testing.T) {
+		for _, compress := range []bool{false, true} {
+			f, _ := newTestRotatingFile(t, Rotation{MaxSize: 1, Compress: compress})
+			dir := filepath.Dir(f.path)
+
+			for _, line := range []string{"1\n", "2\n", "3\n", "4\n", "5\n"} {
+				_, err := f.Write([]byte(line))
+				require.NoError(t, err)
+			}
+			f.pending.Wait()
+
+			suffix := ".log"
+			if compress {
+				suffix += compressSuffix
+			}
+			assert.Equal(t, []string{
+				"app-2025-01-02T03-04-05.000-1" + suffix,
+				"app-2025-01-02T03-04-05.000-2" + suffix,
+				"app-2025-01-02T03-04-05.000-3" + suffix,
+				"app-2025-01-02T03-04-05.000" + suffix,
+				"app.log",
+			}, listDir(t, dir))
+		}
+	})
+
+	t.Run("should keep newest backups rotated in the same millisecond", func(t *testing.T) {
+		f, _ := newTestRotatingFile(t, Rotation{MaxBackups: 2})
+		dir := filepath.Dir(f.path)
+
+		for range 4 {
+			require.NoError(t, f.Rotate())
+		}
+
+		assert.Equal(t, []string{
+			"app-2025-01-02T03-04-05.000-2.log",
+			"app-2025-01-02T03-04-05.000-3.log",
+			"app.log",
+		}, listDir(t, dir))
+	})
+
+	t.Run("should reopen moved file", func(t *testing.T) {
+		f, _ := newTestRotatingFile(t, Rotation{})
+		dir := filepath.Dir(f.path)
+		moved := filepath.Join(dir, "app.log.1")
+
+		_, err := f.Write([]byte("before\n"))
+		require.NoError(t, err)
+		require.NoError(t, os.Rename(f.path, moved))
+
+		signals := make(chan os.Signal)
+		go f.reopenOn(signals)
+		signals <- syscall.SIGHUP
+		signals <- syscall.SIGHUP
+
+		_, err = f.Write([]byte("after\n"))
+		require.NoError(t, err)
+
+		assert.Equal(t, "before\n", readFile(t, moved))
+		assert.Equal(t, "after\n", readFile(t, f.path))
+	})
+
+	t.Run("should fail after close", func(t *testing.T) {
+		f, _ := newTestRotatingFile(t, Rotation{ReopenOnSIGHUP: true})
+
+		require.NoError(t, f.Close())
+		require.NoError(t, f.Close())
+
+		_, err := f.Write([]byte("closed\n"))
+		assert.ErrorIs(t, err, os.ErrClosed)
+		assert.ErrorIs(t, f.Sync(), os.ErrClosed)
+		assert.ErrorIs(t, f.Rotate(), os.ErrClosed)
+		assert.ErrorIs(t, f.Reopen(), os.ErrClosed)
+	})
+
+	t.Run("should rotate file outputs of every backend", func(t *testing.T) {
+		for _, backend := range []Backend{ZapBackend, SlogBackend} {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "app.log")
+
+			logger, err := NewLogger(Config{
+				Backend:  backend,
+				Outputs:  []string{path},
+				Rotation: &Rotation{MaxSize: 1},
+			})
+			require.NoError(t, err)
+
+			logger.Info("first")
+			logger.Info("second")
+			logger.Sync()
+
+			names := listDir(t, dir)
+			require.Len(t, names, 2)
+			assert.Contains(t, readFile(t, filepath.Join(dir, names[0])), "first")
+			assert.Contains(t, readFile(t, path), "second")
+		}
+	})
+}
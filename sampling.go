@@ -0,0 +1,267 @@
+package azalogger
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const droppedSummaryMessage = "log entries dropped"
+
+// SamplingConfig configures sampling and rate limiting per level
+// It replaces the default sampler of zap production config
+type SamplingConfig struct {
+	// Tick is the sampling period, defaults to one second
+	Tick time.Duration
+	// Levels not listed are never sampled, FatalLevel is never sampled
+	Levels map[LogLevel]SamplingRule
+	// SummaryInterval logs a warn summary of dropped entries at most once per interval
+	// 0 disables the summary
+	SummaryInterval time.Duration
+}
+
+// SamplingRule logs the First entries with the same level and message per tick,
+// then every Thereafter entry. The rate limiter then applies to the whole level.
+type SamplingRule struct {
+	First      int
+	Thereafter int
+	// Rate of entries per second, 0 disables rate limiting
+	Rate float64
+	// Burst of the rate limiter, defaults to Rate rounded up
+	Burst int
+}
+
+// Stats counts entries dropped by the logger and all its children
+type Stats struct {
+	Sampled     uint64
+	RateLimited uint64
+	// AsyncDropped counts entries dropped by the async queue overflow policy
+	AsyncDropped uint64
+}
+
+// StatsProvider is implemented by loggers returned by NewLogger and NewInMemoryLogger
+// Stats are shared with child loggers
+type StatsProvider interface {
+	Stats() Stats
+}
+
+type samplingKey struct {
+	level LogLevel
+	msg   string
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type sampler struct {
+	cfg SamplingConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	tickStart time.Time
+	counts    map[samplingKey]int
+	buckets   map[LogLevel]*tokenBucket
+
+	stats        Stats
+	summaryStats Stats
+	lastSummary  time.Time
+}
+
+// newSampler returns nil when cfg is nil, a nil sampler allows everything
+func newSampler(cfg *SamplingConfig) *sampler {
+	if cfg == nil {
+		return nil
+	}
+
+	s := &sampler{
+		cfg:     *cfg,
+		now:     time.Now,
+		counts:  make(map[samplingKey]int),
+		buckets: make(map[LogLevel]*tokenBucket),
+	}
+	if s.cfg.Tick <= 0 {
+		s.cfg.Tick = time.Second
+	}
+	return s
+}
+
+func (s *sampler) allow(level LogLevel, msg string) bool {
+	if s == nil || level == FatalLevel {
+		return true
+	}
+	rule, ok := s.cfg.Levels[level]
+	if !ok {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if rule.First > 0 && !s.sample(now, rule, samplingKey{level: level, msg: msg}) {
+		s.stats.Sampled++
+		s.summaryStats.Sampled++
+		return false
+	}
+	if rule.Rate > 0 && !s.take(now, rule, level) {
+		s.stats.RateLimited++
+		s.summaryStats.RateLimited++
+		return false
+	}
+	return true
+}
+
+func (s *sampler) sample(now time.Time, rule SamplingRule, key samplingKey) bool {
+	if now.Sub(s.tickStart) >= s.cfg.Tick {
+		s.tickStart = now
+		clear(s.counts)
+	}
+
+	s.counts[key]++
+	count := s.counts[key]
+	if count <= rule.First {
+		return true
+	}
+	return rule.Thereafter > 0 && (count-rule.First)%rule.Thereafter == 0
+}
+
+func (s *sampler) take(now time.Time, rule SamplingRule, level LogLevel) bool {
+	burst := float64(rule.Burst)
+	if burst <= 0 {
+		burst = math.Ceil(rule.Rate)
+	}
+
+	bucket, ok := s.buckets[level]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, last: now}
+		s.buckets[level] = bucket
+	}
+
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.last).Seconds()*rule.Rate)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// summary returns the dropped counters since last summary
+// when SummaryInterval elapsed and entries were dropped
+func (s *sampler) summary() (Stats, bool) {
+	if s == nil || s.cfg.SummaryInterval <= 0 {
+		return Stats{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.summaryStats == (Stats{}) || now.Sub(s.lastSummary) < s.cfg.SummaryInterval {
+		return Stats{}, false
+	}
+
+	stats := s.summaryStats
+	s.summaryStats = Stats{}
+	s.lastSummary = now
+	return stats, true
+}
+
+func (s *sampler) Stats() Stats {
+	if s == nil {
+		return Stats{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// samplingCore applies the sampler on zap entries
+type samplingCore struct {
+	zapcore.Core
+	sampler *sampler
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{Core: c.Core.With(fields), sampler: c.sampler}
+}
+
+func (c *samplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) || !c.sampler.allow(fromZapLevel(ent.Level), ent.Message) {
+		return ce
+	}
+
+	if stats, ok := c.sampler.summary(); ok && c.Enabled(zapcore.WarnLevel) {
+		summary := zapcore.Entry{Level: zapcore.WarnLevel, Time: ent.Time, Message: droppedSummaryMessage}
+		_ = c.Core.Write(summary, []zapcore.Field{
+			zap.Uint64("sampled", stats.Sampled),
+			zap.Uint64("rate_limited", stats.RateLimited),
+		})
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// samplingHandler applies the sampler on slog records
+type samplingHandler struct {
+	slog.Handler
+	sampler *sampler
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithGroup(name), sampler: h.sampler}
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.sampler.allow(fromSlogLevel(r.Level), r.Message) {
+		return nil
+	}
+
+	if stats, ok := h.sampler.summary(); ok && h.Enabled(ctx, slog.LevelWarn) {
+		summary := slog.NewRecord(r.Time, slog.LevelWarn, droppedSummaryMessage, 0)
+		summary.AddAttrs(slog.Uint64("sampled", stats.Sampled), slog.Uint64("rate_limited", stats.RateLimited))
+		_ = h.Handler.Handle(ctx, summary)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func fromZapLevel(level zapcore.Level) LogLevel {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return DebugLevel
+	case level == zapcore.InfoLevel:
+		return InfoLevel
+	case level == zapcore.WarnLevel:
+		return WarnLevel
+	case level == zapcore.ErrorLevel:
+		return ErrorLevel
+	default:
+		return FatalLevel
+	}
+}
+
+func fromSlogLevel(level slog.Level) LogLevel {
+	switch {
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	case level < slogFatalLevel:
+		return ErrorLevel
+	default:
+		return FatalLevel
+	}
+}
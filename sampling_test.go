@@ -0,0 +1,168 @@
+package azalogger
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSampler(cfg SamplingConfig) (*sampler, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+	s := newSampler(&cfg)
+	s.now = clock.Now
+	return s, clock
+}
+
+func countAllowed(s *sampler, level LogLevel, msg string, n int) int {
+	allowed := 0
+	for range n {
+		if s.allow(level, msg) {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestSampler(t *testing.T) {
+	t.Run("should allow everything when not configured", func(t *testing.T) {
+		var s *sampler
+
+		assert.Nil(t, newSampler(nil))
+		assert.Equal(t, 10, countAllowed(s, InfoLevel, "msg", 10))
+		assert.Equal(t, Stats{}, s.Stats())
+	})
+
+	t.Run("should log first then every thereafter per level and message", func(t *testing.T) {
+		s, clock := newTestSampler(SamplingConfig{
+			Levels: map[LogLevel]SamplingRule{InfoLevel: {First: 2, Thereafter: 3}},
+		})
+
+		assert.Equal(t, 4, countAllowed(s, InfoLevel, "hot", 10))
+		assert.Equal(t, 2, countAllowed(s, InfoLevel, "other", 2))
+		assert.Equal(t, 10, countAllowed(s, WarnLevel, "hot", 10))
+		assert.Equal(t, Stats{Sampled: 6}, s.Stats())
+
+		clock.Add(time.Second)
+		assert.Equal(t, 2, countAllowed(s, InfoLevel, "hot", 2))
+	})
+
+	t.Run("should drop everything after first without thereafter", func(t *testing.T) {
+		s, _ := newTestSampler(SamplingConfig{
+			Levels: map[LogLevel]SamplingRule{DebugLevel: {First: 1}},
+		})
+
+		assert.Equal(t, 1, countAllowed(s, DebugLevel, "hot", 10))
+	})
+
+	t.Run("should never sample fatal", func(t *testing.T) {
+		s, _ := newTestSampler(SamplingConfig{
+			Levels: map[LogLevel]SamplingRule{FatalLevel: {First: 1}},
+		})
+
+		assert.Equal(t, 5, countAllowed(s, FatalLevel, "hot", 5))
+	})
+
+	t.Run("should rate limit per level", func(t *testing.T) {
+		s, clock := newTestSampler(SamplingConfig{
+			Levels: map[LogLevel]SamplingRule{ErrorLevel: {Rate: 2, Burst: 3}},
+		})
+
+		assert.Equal(t, 3, countAllowed(s, ErrorLevel, "a", 5))
+		clock.Add(500 * time.Millisecond)
+		assert.Equal(t, 1, countAllowed(s, ErrorLevel, "b", 5))
+		clock.Add(10 * time.Second)
+		assert.Equal(t, 3, countAllowed(s, ErrorLevel, "c", 5))
+		assert.Equal(t, Stats{RateLimited: 8}, s.Stats())
+	})
+
+	t.Run("should default burst to rate", func(t *testing.T) {
+		s, _ := newTestSampler(SamplingConfig{
+			Levels: map[LogLevel]SamplingRule{InfoLevel: {Rate: 1.5}},
+		})
+
+		assert.Equal(t, 2, countAllowed(s, InfoLevel, "msg", 5))
+	})
+
+	t.Run("should summarize dropped entries once per interval", func(t *testing.T) {
+		s, clock := newTestSampler(SamplingConfig{
+			Levels:          map[LogLevel]SamplingRule{InfoLevel: {First: 1, Rate: 100}},
+			SummaryInterval: time.Minute,
+		})
+
+		_, ok := s.summary()
+		assert.False(t, ok)
+
+		countAllowed(s, InfoLevel, "msg", 3)
+		stats, ok := s.summary()
+		require.True(t, ok)
+		assert.Equal(t, Stats{Sampled: 2}, stats)
+
+		countAllowed(s, InfoLevel, "msg", 3)
+		_, ok = s.summary()
+		assert.False(t, ok)
+
+		clock.Add(time.Minute)
+		stats, ok = s.summary()
+		require.True(t, ok)
+		assert.Equal(t, Stats{Sampled: 3}, stats)
+		assert.Equal(t, Stats{Sampled: 5}, s.Stats())
+	})
+}
+
+func TestSampling(t *testing.T) {
+	cfg := Config{
+		LogLevel: InfoLevel,
+		Sampling: &SamplingConfig{
+			Levels:          map[LogLevel]SamplingRule{InfoLevel: {First: 1}},
+			SummaryInterval: time.Nanosecond,
+		},
+	}
+
+	logAll := func(logger Logger) {
+		child := logger.With("app", "myapp")
+		child.Info("hot")
+		child.Info("hot")
+		logger.Info("hot")
+		logger.Warn("other")
+	}
+
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	for name, backend := range backends {
+		t.Run("should sample "+name+" logger", func(t *testing.T) {
+			var buff bytes.Buffer
+			backendCfg := cfg
+			backendCfg.Backend = backend
+			backendCfg.Writers = []io.Writer{&buff}
+
+			logger, err := NewLogger(backendCfg)
+			require.NoError(t, err)
+			logAll(logger)
+
+			lines := strings.Split(strings.TrimSpace(buff.String()), "\n")
+			require.Len(t, lines, 3)
+			assert.Contains(t, lines[0], "hot")
+			assert.Contains(t, lines[1], droppedSummaryMessage)
+			assert.Contains(t, lines[1], `"sampled":2,"rate_limited":0`)
+			assert.Contains(t, lines[2], "other")
+			assert.Equal(t, Stats{Sampled: 2}, logger.(StatsProvider).Stats())
+			assert.Equal(t, Stats{Sampled: 2}, logger.With("foo", "bar").(StatsProvider).Stats())
+		})
+	}
+
+	t.Run("should sample in-memory logger", func(t *testing.T) {
+		logger := NewInMemoryLogger(cfg)
+		logAll(logger)
+
+		entries := logger.All()
+		require.Len(t, entries, 3)
+		assert.Equal(t, "[INFO] hot app=myapp", entries[0].String())
+		assert.Equal(t, "[WARN] log entries dropped sampled=2 rate_limited=0", entries[1].String())
+		assert.Equal(t, "[WARN] other", entries[2].String())
+		assert.Equal(t, Stats{Sampled: 2}, logger.Stats())
+	})
+}
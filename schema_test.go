@@ -0,0 +1,179 @@
+package azalogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+// noopFatalHook lets zap log a fatal entry without exiting, zap refusing zapcore.WriteThenNoop
+type noopFatalHook struct{}
+
+func (noopFatalHook) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {}
+
+// logSchemaScenario runs the same calls through any backend
+func logSchemaScenario(logger Logger) {
+	err := errors.New("boom")
+	at := time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	}))
+
+	logger.Debug("debug message", "user", "bob")
+	logger.Info("info message", "count", 2, "ok", true, "ratio", 0.5, "latency", time.Millisecond, "at", at)
+	logger.Warn("warn message", "error", err)
+	logger.Error("error message", "error", err)
+	logger.Log(InfoLevel, "typed message", String("user", "bob"), Int("count", 2), Duration("latency", time.Millisecond),
+		Time("at", at), Err(err), Object("req", String("method", "GET"), Int("status", 200)))
+	logger.Log(ErrorLevel, "typed error message", Err(err))
+	logger.With("app", "myapp").Info("with message", "user", "bob")
+	logger.WithContext(ctx).Info("context message")
+	logger.Fatal("fatal message")
+	logger.Sync()
+}
+
+// normalizeSchema replaces values changing between runs and backends with placeholders, keeping key order
+// format is the one used by backends, see Config.format
+func normalizeSchema(t *testing.T, output []byte, format Format) []byte {
+	t.Helper()
+
+	var normalized bytes.Buffer
+	for line := range bytes.Lines(output) {
+		fields, err := parseJSONObject(line)
+		require.NoError(t, err, string(line))
+		for i, field := range fields {
+			switch field.key {
+			case format.TimeKey:
+				var timestamp string
+				require.NoError(t, json.Unmarshal(field.value, &timestamp))
+				_, err := time.Parse(format.TimeFormat, timestamp)
+				require.NoError(t, err)
+				fields[i].value = json.RawMessage(`"<timestamp>"`)
+			case format.stacktraceKey():
+				fields[i].value = json.RawMessage(`"<stacktrace>"`)
+			// keep file name only, the directory depends on the checkout
+			case CallerKey:
+				fields[i].value = normalizeFile(t, field.value)
+			case GCPSourceLocationKey:
+				fields[i].value = normalizeFile(t, field.value, "file")
+			case ECSOriginKey:
+				fields[i].value = normalizeFile(t, field.value, "file", "name")
+			}
+		}
+		writeJSONObject(&normalized, fields)
+		normalized.WriteByte('\n')
+	}
+	return normalized.Bytes()
+}
+
+// normalizeFile keeps the file name of the path found under keys of nested objects
+func normalizeFile(t *testing.T, value json.RawMessage, keys ...string) json.RawMessage {
+	t.Helper()
+
+	if len(keys) == 0 {
+		var file string
+		require.NoError(t, json.Unmarshal(value, &file))
+		encoded, err := json.Marshal(filepath.Base(file))
+		require.NoError(t, err)
+		return encoded
+	}
+
+	fields, err := parseJSONObject(value)
+	require.NoError(t, err, string(value))
+	for i, field := range fields {
+		if field.key == keys[0] {
+			fields[i].value = normalizeFile(t, field.value, keys[1:]...)
+		}
+	}
+	var normalized bytes.Buffer
+	writeJSONObject(&normalized, fields)
+	return normalized.Bytes()
+}
+
+func writeJSONObject(buff *bytes.Buffer, fields []jsonField) {
+	buff.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			buff.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.key)
+		buff.Write(key)
+		buff.WriteByte(':')
+		buff.Write(field.value)
+	}
+	buff.WriteByte('}')
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll("testdata", 0o750))
+		require.NoError(t, os.WriteFile(path, got, 0o600))
+	}
+
+	expected, err := os.ReadFile(filepath.Clean(path))
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(got))
+}
+
+// noFatalExit lets backends log fatal entries without exiting
+func noFatalExit(t *testing.T, logger Logger) {
+	t.Helper()
+
+	osExit = func(int) {}
+	t.Cleanup(func() { osExit = os.Exit })
+	if l, ok := logger.(*zapLogger); ok {
+		l.base = l.base.WithOptions(zap.WithFatalHook(noopFatalHook{}))
+		l.logger = l.base.Sugar()
+	}
+}
+
+// newBufferedLogger builds a logger from cfg which also writes to the returned buffer
+func newBufferedLogger(tb testing.TB, cfg Config) (Logger, *bytes.Buffer) {
+	tb.Helper()
+
+	var buff bytes.Buffer
+	cfg.Writers = append(cfg.Writers, &buff)
+	logger, err := NewLogger(cfg)
+	require.NoError(tb, err)
+	return logger, &buff
+}
+
+func TestSchema(t *testing.T) {
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	for _, env := range []Environment{ProdEnvironment, DevEnvironment} {
+		for name, backend := range backends {
+			t.Run(string(env)+"/"+name, func(t *testing.T) {
+				logger, buff := newBufferedLogger(t, Config{
+					Backend:  backend,
+					Env:      env,
+					LogLevel: DebugLevel,
+					// dev defaults to console, the schema is compared on JSON
+					Format: Format{Encoding: JSONEncoding},
+				})
+				noFatalExit(t, logger)
+
+				logSchemaScenario(logger)
+
+				assertGolden(t, "schema_"+string(env), normalizeSchema(t, buff.Bytes(), Format{}.withDefaults(env)))
+			})
+		}
+	}
+}
@@ -2,93 +2,283 @@ package azalogger
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"os"
-	"runtime/debug"
-
-	"go.opentelemetry.io/otel/trace"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 type slogLogger struct {
-	logger *slog.Logger
-	level  *slog.LevelVar
-	env    Environment
+	logger    *slog.Logger
+	level     *slog.LevelVar
+	levels    *levelController
+	env       Environment
+	caller    bool
+	out       io.Writer
+	sampler   *sampler
+	async     *asyncWriter
+	closer    *outputCloser
+	redactor  *redactor
+	format    Format
+	extractor *contextExtractor
+	span      *spanRecorder
+}
+
+// slogFatalLevel is above slog.LevelError so fatal entries are encoded as fatal
+const slogFatalLevel = slog.LevelError + 4
+
+// osExit is replaced in tests to log fatal entries without exiting
+var osExit = os.Exit
+
+var slogAttrsPool = sync.Pool{
+	New: func() any {
+		attrs := make([]slog.Attr, 0, 16)
+		return &attrs
+	},
+}
+
+func (l *slogLogger) Debug(msg string, kv ...any) {
+	l.logCaller(l.callerPC(DebugLevel), DebugLevel, msg, kv)
+}
+
+func (l *slogLogger) Info(msg string, kv ...any) {
+	l.logCaller(l.callerPC(InfoLevel), InfoLevel, msg, kv)
+}
+
+func (l *slogLogger) Warn(msg string, kv ...any) {
+	l.logCaller(l.callerPC(WarnLevel), WarnLevel, msg, kv)
 }
 
-func (l *slogLogger) Debug(msg string, kv ...any) { l.logger.Debug(msg, kv...) }
-func (l *slogLogger) Info(msg string, kv ...any)  { l.logger.Info(msg, kv...) }
-func (l *slogLogger) Warn(msg string, kv ...any)  { l.logger.Warn(msg, kv...) }
 func (l *slogLogger) Error(msg string, kv ...any) {
-	if l.env == DevEnvironment {
-		kv = append(kv, "stack", string(debug.Stack()))
-	}
-	l.logger.Error(msg, kv...)
+	l.logCaller(l.callerPC(ErrorLevel), ErrorLevel, msg, kv)
 }
 
 func (l *slogLogger) Fatal(msg string, kv ...any) {
-	if l.env == DevEnvironment {
-		kv = append(kv, "stack", string(debug.Stack()))
+	l.logCaller(l.callerPC(FatalLevel), FatalLevel, msg, kv)
+	l.Sync()
+	osExit(1)
+}
+
+// kv redacts kv and records them on the span of WithContext
+func (l *slogLogger) kv(level LogLevel, msg string, kv []any) []any {
+	kv = l.redactor.kv(kv)
+	l.span.kv(level, msg, kv)
+	return kv
+}
+
+// logCaller logs kv with pc as caller, see callerLogger
+func (l *slogLogger) logCaller(pc uintptr, level LogLevel, msg string, kv []any) {
+	slogLevel := toSlogLevel(level)
+	kv = l.kv(level, msg, kv)
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, slogLevel) {
+		return
+	}
+	if l.hasStacktrace(level) {
+		kv = append(kv, l.format.stacktraceKey(), stacktrace(pc))
+	}
+	if !l.caller {
+		pc = 0
 	}
-	l.logger.Error(msg, kv...)
-	os.Exit(1)
+
+	r := slog.NewRecord(time.Now(), slogLevel, msg, pc)
+	r.Add(kv...)
+	_ = l.logger.Handler().Handle(ctx, r)
 }
 
-// Sync -> NOOP
-func (l *slogLogger) Sync() {}
+// hasStacktrace follows zap: stacktraces from warn in DevEnvironment, from error otherwise
+func (l *slogLogger) hasStacktrace(level LogLevel) bool {
+	switch level {
+	case ErrorLevel, FatalLevel:
+		return true
+	case WarnLevel:
+		return l.env == DevEnvironment
+	default:
+		return false
+	}
+}
 
-func (l *slogLogger) With(kv ...any) Logger {
-	return &slogLogger{logger: l.logger.With(kv...)}
+// callerPC returns the pc of the caller of the function calling it
+// It's 0 when the entry is not written or has neither caller nor stacktrace, runtime.Callers being costly
+func (l *slogLogger) callerPC(level LogLevel) uintptr {
+	if !l.caller && !l.hasStacktrace(level) || !l.logger.Enabled(context.Background(), toSlogLevel(level)) {
+		return 0
+	}
+
+	var pcs [1]uintptr
+	// skip runtime.Callers, callerPC and the function calling it
+	runtime.Callers(3, pcs[:])
+	return pcs[0]
 }
 
-func (l *slogLogger) WithContext(ctx context.Context) Logger {
-	span := trace.SpanFromContext(ctx)
-	spanCtx := span.SpanContext()
+// stacktrace formats the stack like zap, from the frame of pc which is the caller of the logging call
+// Without pc, it starts at the caller of the function calling it
+func stacktrace(pc uintptr) string {
+	// skip runtime.Callers and stacktrace
+	pcs := callers(2)
+	if i := slices.Index(pcs, pc); pc != 0 && i >= 0 {
+		pcs = pcs[i:]
+	} else if len(pcs) > 1 {
+		pcs = pcs[1:]
+	}
+	return formatFrames(runtime.CallersFrames(pcs))
+}
 
-	if !spanCtx.IsValid() {
-		return l
+// callers returns the whole stack, skipping like runtime.Callers
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip, pcs)
+	for n == len(pcs) {
+		pcs = make([]uintptr, len(pcs)*2)
+		n = runtime.Callers(skip, pcs)
 	}
+	return pcs[:n]
+}
 
-	return l.With("trace_id", spanCtx.TraceID().String(),
-		"span_id", spanCtx.SpanID().String())
+// formatFrames writes a function line and a tab indented file:line line per frame
+func formatFrames(frames *runtime.Frames) string {
+	var b strings.Builder
+	// the last frame is runtime.main or runtime.goexit, zap ignores it too
+	for frame, more := frames.Next(); more; frame, more = frames.Next() {
+		writeFrame(&b, frame)
+	}
+	return b.String()
 }
 
-func (l *slogLogger) HTTPLevelHandler(authHandler AuthorizationHandler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if authHandler != nil && !authHandler(r) {
-			http.Error(w, "unauthorized", http.StatusForbidden)
-			return
-		}
+func writeFrame(b *strings.Builder, frame runtime.Frame) {
+	if b.Len() > 0 {
+		b.WriteByte('\n')
+	}
+	b.WriteString(frame.Function)
+	b.WriteString("\n\t")
+	b.WriteString(frame.File)
+	b.WriteByte(':')
+	b.WriteString(strconv.Itoa(frame.Line))
+}
 
-		switch r.Method {
-		case http.MethodGet:
-			level := l.level.Level().String()
-			w.Header().Set("Content-Type", "application/json")
-			_ = json.NewEncoder(w).Encode(map[string]string{"level": level})
-			w.WriteHeader(http.StatusOK)
-		case http.MethodPut:
-			var payload struct {
-				Level string `json:"level"`
-			}
-			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-				http.Error(w, "invalid payload", http.StatusBadRequest)
-				return
-			}
+func (l *slogLogger) Log(level LogLevel, msg string, fields ...Field) {
+	l.logFields(l.callerPC(level), level, msg, "", fields)
+}
 
-			newLevel, err := parseSlogLevel(payload.Level)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
+// logStack logs fields with stack as stacktrace, see stackLogger
+func (l *slogLogger) logStack(level LogLevel, msg, stack string, fields []Field) {
+	l.logFields(l.callerPC(level), level, msg, stack, fields)
+}
+
+// logFields logs fields with pc as caller, the stacktrace is stack when set
+func (l *slogLogger) logFields(pc uintptr, level LogLevel, msg, stack string, fields []Field) {
+	slogLevel, err := parseSlogLevel(level.String())
+	if err != nil {
+		level, slogLevel = InfoLevel, slog.LevelInfo
+	}
+	if level == FatalLevel {
+		slogLevel = slogFatalLevel
+	}
+	if l.span.enabled(level) {
+		// cloned so fields doesn't escape when span events are off
+		redacted, _ := l.redactor.fields(slices.Clone(fields))
+		l.span.fields(level, msg, redacted)
+	}
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, slogLevel) {
+		return
+	}
 
-			l.level.Set(newLevel)
-			w.WriteHeader(http.StatusOK)
-		default:
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	attrs := slogAttrsPool.Get().(*[]slog.Attr)
+	for _, field := range fields {
+		if field.Type != SkipType {
+			field, _ = l.redactor.field(field)
+			*attrs = append(*attrs, toSlogAttr(field))
 		}
-	})
+	}
+	if stack == "" && l.hasStacktrace(level) {
+		stack = stacktrace(pc)
+	}
+	if stack != "" {
+		*attrs = append(*attrs, slog.String(l.format.stacktraceKey(), stack))
+	}
+	if !l.caller {
+		pc = 0
+	}
+	r := slog.NewRecord(time.Now(), slogLevel, msg, pc)
+	r.AddAttrs(*attrs...)
+	_ = l.logger.Handler().Handle(ctx, r)
+
+	clear(*attrs)
+	*attrs = (*attrs)[:0]
+	slogAttrsPool.Put(attrs)
+
+	if level == FatalLevel {
+		l.Sync()
+		osExit(1)
+	}
+}
+
+// Sync flushes async queue and syncs outputs supporting it
+func (l *slogLogger) Sync() {
+	if syncer, ok := l.out.(interface{ Sync() error }); ok {
+		_ = syncer.Sync()
+	}
+}
+
+// Close writes queued async entries, stops the async goroutine and closes files opened from Outputs
+// The logger and loggers derived from it must not be used afterwards
+func (l *slogLogger) Close() error { return l.closer.Close() }
+
+func (l *slogLogger) With(kv ...any) Logger {
+	return &slogLogger{
+		logger:    l.logger.With(l.redactor.kv(kv)...),
+		level:     l.level,
+		levels:    l.levels,
+		env:       l.env,
+		caller:    l.caller,
+		out:       l.out,
+		sampler:   l.sampler,
+		async:     l.async,
+		closer:    l.closer,
+		redactor:  l.redactor,
+		format:    l.format,
+		extractor: l.extractor,
+		span:      l.span,
+	}
+}
+
+func (l *slogLogger) WithContext(ctx context.Context) Logger {
+	kv := l.extractor.fields(ctx)
+	if len(kv) == 0 {
+		return l
+	}
+
+	child := l.With(kv...).(*slogLogger)
+	child.span = l.extractor.spanRecorder(ctx)
+	return child
+}
+
+func (l *slogLogger) HTTPLevelHandler(authHandler AuthorizationHandler) http.Handler {
+	return l.levels.handler(authHandler)
+}
+
+func toSlogLevel(level LogLevel) slog.Level {
+	switch level {
+	case DebugLevel:
+		return slog.LevelDebug
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	case FatalLevel:
+		return slogFatalLevel
+	default:
+		return slog.LevelInfo
+	}
 }
 
 func parseSlogLevel(level string) (slog.Level, error) {
@@ -106,30 +296,98 @@ func parseSlogLevel(level string) (slog.Level, error) {
 	}
 }
 
+func toSlogAttr(f Field) slog.Attr {
+	switch f.Type {
+	case StringType:
+		return slog.String(f.Key, f.String)
+	case IntType, Int64Type:
+		return slog.Int64(f.Key, f.Integer)
+	case BoolType:
+		return slog.Bool(f.Key, f.Integer == 1)
+	case Float64Type:
+		return slog.Float64(f.Key, math.Float64frombits(uint64(f.Integer)))
+	case DurationType:
+		return slog.Duration(f.Key, time.Duration(f.Integer))
+	case TimeType:
+		return slog.Time(f.Key, f.time())
+	case ObjectType:
+		fields, _ := f.Interface.([]Field)
+		attrs := make([]slog.Attr, 0, len(fields))
+		for _, field := range fields {
+			if field.Type != SkipType {
+				attrs = append(attrs, toSlogAttr(field))
+			}
+		}
+		return slog.Attr{Key: f.Key, Value: slog.GroupValue(attrs...)}
+	default:
+		return slog.Any(f.Key, f.Interface)
+	}
+}
+
 func (l *slogLogger) LogLevel() string {
 	return l.level.Level().String()
 }
 
-func newSlogLogger(cfg Config) *slogLogger {
+func (l *slogLogger) Stats() Stats {
+	stats := l.sampler.Stats()
+	stats.AsyncDropped = l.async.Dropped()
+	return stats
+}
+
+func newSlogLogger(cfg Config) (*slogLogger, error) {
 	logLevel, err := parseSlogLevel(getLogLevel(cfg).String())
 	if err != nil {
 		logLevel = slog.LevelInfo
 	}
 
+	out, closer, err := openOutputs(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	level := &slog.LevelVar{}
 	level.Set(logLevel)
-	var logger *slog.Logger
-	switch cfg.Env {
-	case DevEnvironment:
-		handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level})
-		logger = slog.New(handler)
+	format := cfg.format()
+	var handler slog.Handler
+	switch format.Encoding {
+	case JSONEncoding:
+		handler = slog.NewJSONHandler(out, &slog.HandlerOptions{
+			AddSource:   !cfg.DisableCaller,
+			Level:       level,
+			ReplaceAttr: format.replaceSlogAttr,
+		})
 	default:
-		handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
-		logger = slog.New(handler)
+		// same encoder as the zap backend
+		encoderCfg := createZapConfig(cfg).EncoderConfig
+		handler = newEncoderHandler(format.newZapEncoder(encoderCfg), out, level, !cfg.DisableCaller, format.stacktraceKey())
 	}
-	return &slogLogger{
-		logger: logger,
-		level:  level,
-		env:    cfg.Env,
+
+	if fields := format.staticFields(); len(fields) > 0 {
+		attrs := make([]slog.Attr, 0, len(fields))
+		for _, field := range fields {
+			attrs = append(attrs, toSlogAttr(field))
+		}
+		handler = handler.WithAttrs(attrs)
+	}
+
+	sampler := newSampler(cfg.Sampling)
+	if sampler != nil {
+		handler = &samplingHandler{Handler: handler, sampler: sampler}
 	}
+
+	async, _ := out.(*asyncWriter)
+	return &slogLogger{
+		logger:    slog.New(handler),
+		level:     level,
+		levels:    newSlogLevelController(level),
+		env:       cfg.Env,
+		caller:    !cfg.DisableCaller,
+		out:       out,
+		sampler:   sampler,
+		async:     async,
+		closer:    closer,
+		redactor:  newRedactor(cfg.Redaction),
+		format:    format,
+		extractor: newContextExtractor(cfg, format),
+	}, nil
 }
@@ -0,0 +1,134 @@
+package azalogger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+)
+
+// SlogHandler is a slog.Handler backed by any azalogger Logger (zap, slog or in-memory)
+// It allows third-party libraries accepting a *slog.Logger to go through
+// the configured backend, injected fields and trace correlation
+type SlogHandler struct {
+	logger Logger
+	prefix string
+}
+
+// NewSlogHandler wraps logger into a slog.Handler
+func NewSlogHandler(logger Logger) *SlogHandler {
+	return &SlogHandler{logger: logger}
+}
+
+// ToSlog returns a stdlib *slog.Logger writing through logger
+func ToSlog(logger Logger) *slog.Logger {
+	return slog.New(NewSlogHandler(logger))
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel, err := parseSlogLevel(strings.ToLower(h.logger.LogLevel()))
+	if err != nil {
+		return true
+	}
+	return level >= minLevel
+}
+
+// Handle translates the record into a key/value call on the wrapped logger, reporting the record caller.
+// Levels above error are logged as error, a slog record never exits the process.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	logger := h.logger
+	if ctx != nil {
+		logger = logger.WithContext(ctx)
+	}
+
+	kv := make([]any, 0, r.NumAttrs()*2)
+	r.Attrs(func(attr slog.Attr) bool {
+		kv = appendSlogAttr(kv, h.prefix, attr)
+		return true
+	})
+
+	level := slogToLogLevel(r.Level)
+	if l, ok := logger.(callerLogger); ok && r.PC != 0 {
+		l.logCaller(r.PC, level, r.Message, kv)
+		return nil
+	}
+
+	switch level {
+	case ErrorLevel:
+		logger.Error(r.Message, kv...)
+	case WarnLevel:
+		logger.Warn(r.Message, kv...)
+	case InfoLevel:
+		logger.Info(r.Message, kv...)
+	default:
+		logger.Debug(r.Message, kv...)
+	}
+	return nil
+}
+
+// callerLogger is implemented by backends logging with the caller of the slog record instead of the bridge one
+type callerLogger interface {
+	logCaller(pc uintptr, level LogLevel, msg string, kv []any)
+}
+
+// pcFrame resolves a pc returned by runtime.Callers, like slog.Record.PC
+func pcFrame(pc uintptr) runtime.Frame {
+	if pc == 0 {
+		return runtime.Frame{}
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return frame
+}
+
+func slogToLogLevel(level slog.Level) LogLevel {
+	switch {
+	case level >= slog.LevelError:
+		return ErrorLevel
+	case level >= slog.LevelWarn:
+		return WarnLevel
+	case level >= slog.LevelInfo:
+		return InfoLevel
+	default:
+		return DebugLevel
+	}
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	kv := make([]any, 0, len(attrs)*2)
+	for _, attr := range attrs {
+		kv = appendSlogAttr(kv, h.prefix, attr)
+	}
+	return &SlogHandler{logger: h.logger.With(kv...), prefix: h.prefix}
+}
+
+// WithGroup qualifies following keys with the group name, groups are flattened with a dot
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{logger: h.logger, prefix: h.prefix + name + "."}
+}
+
+func appendSlogAttr(kv []any, prefix string, attr slog.Attr) []any {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return kv
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix = prefix + attr.Key + "."
+		}
+		for _, groupAttr := range attr.Value.Group() {
+			kv = appendSlogAttr(kv, groupPrefix, groupAttr)
+		}
+		return kv
+	}
+
+	return append(kv, prefix+attr.Key, attr.Value.Any())
+}
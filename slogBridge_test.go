@@ -0,0 +1,102 @@
+package azalogger
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToSlog(t *testing.T) {
+	t.Run("should log through wrapped logger", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{LogLevel: DebugLevel})
+
+		slogger := ToSlog(logger)
+		slogger.Debug("debug log", "app", "myapp")
+		slogger.Info("info log", "app", "myapp")
+		slogger.Warn("warn log", "app", "myapp")
+		slogger.Error("error log", "app", "myapp")
+		slogger.Log(context.Background(), slog.LevelError+4, "above error log")
+
+		entries := logger.Entries()
+		require.Len(t, entries, 6)
+		assert.Equal(t, "[DEBUG] debug log app=myapp", entries[0])
+		assert.Equal(t, "[INFO] info log app=myapp", entries[1])
+		assert.Equal(t, "[WARN] warn log app=myapp", entries[2])
+		assert.Equal(t, "[ERROR] error log app=myapp", entries[3])
+		assert.Equal(t, "[ERROR] above error log", entries[4])
+		for _, entry := range logger.All() {
+			assert.Contains(t, entry.Caller, "slogBridge_test.go:")
+		}
+	})
+
+	t.Run("should flatten groups", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{LogLevel: InfoLevel})
+
+		slogger := ToSlog(logger).WithGroup("http")
+		slogger.Info("request", "method", "GET", slog.Group("req", slog.Int("id", 1)))
+
+		entries := logger.Entries()
+		require.Len(t, entries, 2)
+		assert.Equal(t, "[INFO] request http.method=GET http.req.id=1", entries[0])
+	})
+
+	t.Run("should redact groups and keys of sensitive groups", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{LogLevel: InfoLevel, Redaction: &RedactionConfig{Keys: []string{"credentials", "password"}}})
+
+		slogger := ToSlog(logger)
+		slogger.Info("login", slog.Group("credentials", slog.String("secret", "s3cr3t")),
+			slog.Group("auth", slog.String("password", "s3cr3t"), slog.String("user", "bob")))
+		slogger.WithGroup("credentials").Info("token", "value", "s3cr3t")
+
+		entries := logger.Entries()
+		require.Len(t, entries, 3)
+		assert.Equal(t, "[INFO] login credentials.secret=[REDACTED] auth.password=[REDACTED] auth.user=bob", entries[0])
+		assert.Equal(t, "[INFO] token credentials.value=[REDACTED]", entries[1])
+	})
+
+	t.Run("should inline groups without key and skip empty attrs", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{LogLevel: InfoLevel})
+
+		ToSlog(logger).Info("inline", slog.Group("", slog.String("foo", "bar")), slog.Attr{})
+
+		entries := logger.Entries()
+		require.Len(t, entries, 2)
+		assert.Equal(t, "[INFO] inline foo=bar", entries[0])
+	})
+
+	t.Run("should inject attrs into wrapped logger", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{LogLevel: InfoLevel})
+
+		slogger := ToSlog(logger).WithGroup("svc").With("app", "myapp")
+		slogger.Info("with attrs")
+
+		entries := logger.Entries()
+		require.Len(t, entries, 2)
+		assert.Equal(t, "[INFO] with attrs svc.app=myapp", entries[0])
+	})
+
+	t.Run("should respect wrapped logger level", func(t *testing.T) {
+		handler := NewSlogHandler(NewInMemoryLogger(Config{LogLevel: WarnLevel}))
+
+		assert.False(t, handler.Enabled(context.Background(), slog.LevelDebug))
+		assert.False(t, handler.Enabled(context.Background(), slog.LevelInfo))
+		assert.True(t, handler.Enabled(context.Background(), slog.LevelWarn))
+		assert.True(t, handler.Enabled(context.Background(), slog.LevelError))
+	})
+
+	t.Run("should respect wrapped logger level for every backend", func(t *testing.T) {
+		zapLog, err := newZapLogger(Config{LogLevel: ErrorLevel})
+		require.NoError(t, err)
+		slogLogger, err := newSlogLogger(Config{LogLevel: ErrorLevel})
+		require.NoError(t, err)
+
+		for _, logger := range []Logger{zapLog, slogLogger} {
+			handler := NewSlogHandler(logger)
+			assert.False(t, handler.Enabled(context.Background(), slog.LevelWarn))
+			assert.True(t, handler.Enabled(context.Background(), slog.LevelError))
+		}
+	})
+}
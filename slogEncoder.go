@@ -0,0 +1,145 @@
+package azalogger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// encoderHandler is a slog.Handler writing records with a zap encoder
+// It renders console and logfmt encodings like the zap backend, slog having no such handlers
+type encoderHandler struct {
+	encoder       zapcore.Encoder
+	out           zapcore.WriteSyncer
+	level         slog.Leveler
+	caller        bool
+	stacktraceKey string
+}
+
+func newEncoderHandler(encoder zapcore.Encoder, out io.Writer, level slog.Leveler, caller bool, stacktraceKey string) *encoderHandler {
+	return &encoderHandler{
+		encoder:       encoder,
+		out:           zapcore.Lock(zapcore.AddSync(out)),
+		level:         level,
+		caller:        caller,
+		stacktraceKey: stacktraceKey,
+	}
+}
+
+func (h *encoderHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle encodes the record, its stacktrace attribute being written as the entry stack
+func (h *encoderHandler) Handle(_ context.Context, r slog.Record) error {
+	ent := zapcore.Entry{
+		Level:   toZapLevel(fromSlogLevel(r.Level)),
+		Time:    r.Time,
+		Message: r.Message,
+	}
+	if h.caller && r.PC != 0 {
+		frame := pcFrame(r.PC)
+		ent.Caller = zapcore.EntryCaller{Defined: true, PC: frame.PC, File: frame.File, Line: frame.Line, Function: frame.Function}
+	}
+
+	fields := zapFieldsPool.Get().(*[]zap.Field)
+	r.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == h.stacktraceKey && attr.Value.Kind() == slog.KindString {
+			ent.Stack = attr.Value.String()
+			return true
+		}
+		*fields = appendZapAttr(*fields, attr)
+		return true
+	})
+
+	buf, err := h.encoder.EncodeEntry(ent, *fields)
+	clear(*fields)
+	*fields = (*fields)[:0]
+	zapFieldsPool.Put(fields)
+	if err != nil {
+		return err
+	}
+	_, err = h.out.Write(buf.Bytes())
+	buf.Free()
+	return err
+}
+
+func (h *encoderHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	clone := *h
+	clone.encoder = h.encoder.Clone()
+	for _, attr := range attrs {
+		for _, field := range appendZapAttr(nil, attr) {
+			field.AddTo(clone.encoder)
+		}
+	}
+	return &clone
+}
+
+func (h *encoderHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := *h
+	clone.encoder = h.encoder.Clone()
+	clone.encoder.OpenNamespace(name)
+	return &clone
+}
+
+// appendZapAttr appends attr converted like slog JSON handler does, empty attributes are ignored
+// and groups without key are inlined
+func appendZapAttr(fields []zap.Field, attr slog.Attr) []zap.Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	value := attr.Value
+	switch value.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(attr.Key, value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(attr.Key, value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(attr.Key, value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(attr.Key, value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(attr.Key, value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(attr.Key, value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(attr.Key, value.Time()))
+	case slog.KindGroup:
+		if attr.Key == "" {
+			for _, nested := range value.Group() {
+				fields = appendZapAttr(fields, nested)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(attr.Key, slogAttrsMarshaler(value.Group())))
+	default:
+		if err, ok := value.Any().(error); ok {
+			return append(fields, zap.String(attr.Key, err.Error()))
+		}
+		return append(fields, zap.Any(attr.Key, value.Any()))
+	}
+}
+
+type slogAttrsMarshaler []slog.Attr
+
+func (attrs slogAttrsMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, attr := range attrs {
+		for _, field := range appendZapAttr(nil, attr) {
+			field.AddTo(enc)
+		}
+	}
+	return nil
+}
@@ -13,55 +13,55 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestCreateSlogLogger(t *testing.T) {
 	t.Run("should create slog logger based on config (dev)", func(t *testing.T) {
-		level := &slog.LevelVar{}
-		level.Set(slog.LevelWarn)
-		expected := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level})
 		cfg := Config{
 			LogLevel: WarnLevel,
 			Env:      DevEnvironment,
 		}
 
-		got := newSlogLogger(cfg)
-		assert.Equal(t, expected, got.logger.Handler())
+		got, err := newSlogLogger(cfg)
+
+		require.NoError(t, err)
+		assert.IsType(t, &encoderHandler{}, got.logger.Handler())
+		assert.Equal(t, slog.LevelWarn, got.level.Level())
+		assert.Equal(t, os.Stderr, got.out)
 	})
 
 	t.Run("should create slog logger based on config (prod)", func(t *testing.T) {
-		level := &slog.LevelVar{}
-		level.Set(slog.LevelWarn)
-		expected := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
 		cfg := Config{
 			LogLevel: WarnLevel,
 			Env:      ProdEnvironment,
 		}
 
-		got := newSlogLogger(cfg)
-		assert.Equal(t, expected, got.logger.Handler())
+		got, err := newSlogLogger(cfg)
+
+		require.NoError(t, err)
+		assert.IsType(t, &slog.JSONHandler{}, got.logger.Handler())
+		assert.Equal(t, slog.LevelWarn, got.level.Level())
+		assert.Equal(t, os.Stderr, got.out)
 	})
 
 	t.Run("should default to prod config and info loglevel when nothing is set", func(t *testing.T) {
-		level := &slog.LevelVar{}
-		level.Set(slog.LevelInfo)
-		expected := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
-		cfg := Config{}
+		got, err := newSlogLogger(Config{})
 
-		got := newSlogLogger(cfg)
-		assert.Equal(t, expected, got.logger.Handler())
+		require.NoError(t, err)
+		assert.IsType(t, &slog.JSONHandler{}, got.logger.Handler())
+		assert.Equal(t, slog.LevelInfo, got.level.Level())
+		assert.Equal(t, os.Stderr, got.out)
 	})
 
 	t.Run("should default to prod config and info loglevel when unknown is set", func(t *testing.T) {
 		t.Setenv(LogLevelEnvVar, "unknown")
 
-		level := &slog.LevelVar{}
-		level.Set(slog.LevelInfo)
-		expected := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
-		cfg := Config{}
+		got, err := newSlogLogger(Config{})
 
-		got := newSlogLogger(cfg)
-		assert.Equal(t, expected, got.logger.Handler())
+		require.NoError(t, err)
+		assert.Equal(t, slog.LevelInfo, got.level.Level())
+		assert.Equal(t, os.Stderr, got.out)
 	})
 }
 
@@ -131,19 +131,21 @@ func TestSlogLogs(t *testing.T) {
 		expectedWarnLogMessage := "a warn message"
 		expectedErrLogMessage := "test err message log"
 
-		// capture stdout
-		saveStdout := os.Stdout
+		// capture stderr
+		saveStderr := os.Stderr
 		r, w, err := os.Pipe()
 		require.NoError(t, err)
-		os.Stdout = w
+		os.Stderr = w
 
 		defer func() {
-			os.Stdout = saveStdout
+			os.Stderr = saveStderr
 			_ = w.Close()
 			_ = r.Close()
 		}()
 
-		logger := newSlogLogger(Config{Env: ProdEnvironment, LogLevel: DebugLevel})
+		logger, err := newSlogLogger(Config{Env: ProdEnvironment, LogLevel: DebugLevel})
+
+		require.NoError(t, err)
 		require.NotNil(t, logger)
 
 		logger.Debug(expectedebugLogMessage)
@@ -156,7 +158,7 @@ func TestSlogLogs(t *testing.T) {
 		logger.Sync()
 
 		_ = w.Close()
-		os.Stdout = saveStdout
+		os.Stderr = saveStderr
 
 		// read captured output
 		var buff bytes.Buffer
@@ -167,33 +169,37 @@ func TestSlogLogs(t *testing.T) {
 		output := buff.String()
 		assert.NotEmpty(t, output)
 		assert.Contains(t, output, expectedebugLogMessage)
-		assert.Contains(t, output, strings.ToUpper(DebugLevel.String()))
+		assert.Contains(t, output, `"level":"`+DebugLevel.String()+`"`)
 		assert.Contains(t, output, expectedInfoLogMessage)
-		assert.Contains(t, output, strings.ToUpper(InfoLevel.String()))
+		assert.Contains(t, output, `"level":"`+InfoLevel.String()+`"`)
 		assert.Contains(t, output, expectedWarnLogMessage)
-		assert.Contains(t, output, strings.ToUpper(WarnLevel.String()))
+		assert.Contains(t, output, `"level":"`+WarnLevel.String()+`"`)
 		assert.Contains(t, output, expectedErrLogMessage)
-		assert.Contains(t, output, strings.ToUpper(ErrorLevel.String()))
+		assert.Contains(t, output, `"level":"`+ErrorLevel.String()+`"`)
 		assert.Contains(t, output, "another test\",\"app\":\"myapp\"")
-		assert.NotContains(t, output, "stack")
+		// like zap production, only error and fatal entries have a stacktrace
+		assert.Equal(t, 1, strings.Count(output, `"stacktrace"`))
+		assert.Regexp(t, `"msg":"`+expectedErrLogMessage+`","stacktrace"`, output)
 	})
 
 	t.Run("should contains stack in dev env", func(t *testing.T) {
 		expectedErrLogMessage := "test err message log"
 
-		// capture stdout
-		saveStdout := os.Stdout
+		// capture stderr
+		saveStderr := os.Stderr
 		r, w, err := os.Pipe()
 		require.NoError(t, err)
-		os.Stdout = w
+		os.Stderr = w
 
 		defer func() {
-			os.Stdout = saveStdout
+			os.Stderr = saveStderr
 			_ = w.Close()
 			_ = r.Close()
 		}()
 
-		logger := newSlogLogger(Config{Env: DevEnvironment, LogLevel: DebugLevel})
+		logger, err := newSlogLogger(Config{Env: DevEnvironment, LogLevel: DebugLevel})
+
+		require.NoError(t, err)
 		require.NotNil(t, logger)
 
 		logger.Error(expectedErrLogMessage)
@@ -201,7 +207,7 @@ func TestSlogLogs(t *testing.T) {
 		logger.Sync()
 
 		_ = w.Close()
-		os.Stdout = saveStdout
+		os.Stderr = saveStderr
 
 		// read captured output
 		var buff bytes.Buffer
@@ -212,14 +218,35 @@ func TestSlogLogs(t *testing.T) {
 		output := buff.String()
 		assert.NotEmpty(t, output)
 		assert.Contains(t, output, expectedErrLogMessage)
-		assert.Contains(t, output, strings.ToUpper(ErrorLevel.String()))
-		assert.Contains(t, output, "stack")
+		assert.Contains(t, output, "\t"+ErrorLevel.String()+"\t")
+		// like zap, the stacktrace starts at the logging call
+		assert.Contains(t, output, "\ngitlab.com/ludovic-alarcon/aza-logger.TestSlogLogs.func2")
+		assert.NotContains(t, output, "goroutine")
+		assert.NotContains(t, output, "slogLogger")
+	})
+}
+
+func TestCallerPC_Slog(t *testing.T) {
+	t.Run("should capture caller of written entries only", func(t *testing.T) {
+		logger := newTestSlogLogger(t, io.Discard, InfoLevel)
+
+		assert.Zero(t, logger.callerPC(DebugLevel))
+		assert.NotZero(t, logger.callerPC(InfoLevel))
+	})
+
+	t.Run("should capture caller for stacktraces when caller is disabled", func(t *testing.T) {
+		logger, err := newSlogLogger(Config{DisableCaller: true, Writers: []io.Writer{io.Discard}})
+		require.NoError(t, err)
+
+		assert.Zero(t, logger.callerPC(InfoLevel))
+		assert.NotZero(t, logger.callerPC(ErrorLevel))
 	})
 }
 
 func TestLogLevel_Slog(t *testing.T) {
 	cfg := Config{LogLevel: WarnLevel}
-	logger := newSlogLogger(cfg)
+	logger, err := newSlogLogger(cfg)
+	require.NoError(t, err)
 
 	require.NotNil(t, logger)
 	assert.Equal(t, strings.ToUpper(WarnLevel.String()), logger.LogLevel())
@@ -233,7 +260,9 @@ func TestHttpLevelHandler_Slog(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		rec := httptest.NewRecorder()
 
-		logger := newSlogLogger(Config{LogLevel: InfoLevel})
+		logger, err := newSlogLogger(Config{LogLevel: InfoLevel})
+
+		require.NoError(t, err)
 		require.NotNil(t, logger)
 
 		handler := logger.HTTPLevelHandler(func(req *http.Request) bool { return true })
@@ -250,7 +279,9 @@ func TestHttpLevelHandler_Slog(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		rec := httptest.NewRecorder()
 
-		logger := newSlogLogger(Config{LogLevel: InfoLevel})
+		logger, err := newSlogLogger(Config{LogLevel: InfoLevel})
+
+		require.NoError(t, err)
 		require.NotNil(t, logger)
 
 		handler := logger.HTTPLevelHandler(func(req *http.Request) bool { return false })
@@ -267,7 +298,9 @@ func TestHttpLevelHandler_Slog(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		rec := httptest.NewRecorder()
 
-		logger := newSlogLogger(Config{LogLevel: InfoLevel})
+		logger, err := newSlogLogger(Config{LogLevel: InfoLevel})
+
+		require.NoError(t, err)
 		require.NotNil(t, logger)
 
 		handler := logger.HTTPLevelHandler(func(req *http.Request) bool { return true })
@@ -284,7 +317,9 @@ func TestHttpLevelHandler_Slog(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		rec := httptest.NewRecorder()
 
-		logger := newSlogLogger(Config{LogLevel: InfoLevel})
+		logger, err := newSlogLogger(Config{LogLevel: InfoLevel})
+
+		require.NoError(t, err)
 		require.NotNil(t, logger)
 
 		handler := logger.HTTPLevelHandler(func(req *http.Request) bool { return true })
@@ -299,7 +334,9 @@ func TestHttpLevelHandler_Slog(t *testing.T) {
 		require.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		logger := newSlogLogger(Config{LogLevel: InfoLevel})
+		logger, err := newSlogLogger(Config{LogLevel: InfoLevel})
+
+		require.NoError(t, err)
 		require.NotNil(t, logger)
 
 		handler := logger.HTTPLevelHandler(func(req *http.Request) bool { return true })
@@ -314,7 +351,9 @@ func TestHttpLevelHandler_Slog(t *testing.T) {
 		require.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		logger := newSlogLogger(Config{LogLevel: InfoLevel})
+		logger, err := newSlogLogger(Config{LogLevel: InfoLevel})
+
+		require.NoError(t, err)
 
 		handler := logger.HTTPLevelHandler(func(req *http.Request) bool { return true })
 		handler.ServeHTTP(rec, req)
@@ -323,3 +362,69 @@ func TestHttpLevelHandler_Slog(t *testing.T) {
 		assert.Equal(t, strings.ToUpper(InfoLevel.String()), logger.LogLevel())
 	})
 }
+
+func TestWith_Slog(t *testing.T) {
+	var buff bytes.Buffer
+	logger := newTestSlogLogger(t, &buff, InfoLevel)
+
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanCtx)
+
+	derived := logger.With("component", "api").WithContext(ctx).With("req", 1)
+
+	t.Run("should log with injected fields", func(t *testing.T) {
+		buff.Reset()
+		derived.Debug("filtered")
+		derived.Info("info log")
+		derived.Warn("warn log")
+		derived.Error("error log")
+		derived.Log(InfoLevel, "typed log", String("foo", "bar"))
+		derived.Sync()
+
+		output := buff.String()
+		assert.NotContains(t, output, "filtered")
+		for _, msg := range []string{"info log", "warn log", "error log", "typed log"} {
+			assert.Contains(t, output, msg+`","component":"api","trace_id":"`+spanCtx.TraceID().String()+
+				`","span_id":"`+spanCtx.SpanID().String()+`","trace_flags":"00","sampled":false,"req":1`)
+		}
+	})
+
+	t.Run("should share level with parent", func(t *testing.T) {
+		assert.Equal(t, strings.ToUpper(InfoLevel.String()), derived.LogLevel())
+
+		body := strings.NewReader(`{"level":"debug"}`)
+		req, err := http.NewRequest("PUT", "/loglevel", body)
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		derived.HTTPLevelHandler(nil).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, strings.ToUpper(DebugLevel.String()), derived.LogLevel())
+		assert.Equal(t, strings.ToUpper(DebugLevel.String()), logger.LogLevel())
+
+		buff.Reset()
+		logger.With("component", "other").Debug("now visible")
+		assert.Contains(t, buff.String(), "now visible")
+	})
+
+	t.Run("should keep dev stack on derived logger", func(t *testing.T) {
+		buff.Reset()
+		devLogger, err := newSlogLogger(Config{
+			Env:     DevEnvironment,
+			Writers: []io.Writer{&buff},
+			Format:  Format{Encoding: JSONEncoding},
+		})
+		require.NoError(t, err)
+
+		devLogger.With("component", "api").Error("error log")
+		devLogger.With("component", "api").Log(ErrorLevel, "typed error log")
+
+		output := buff.String()
+		assert.Contains(t, output, `"msg":"error log","component":"api","stacktrace"`)
+		assert.Contains(t, output, `"msg":"typed error log","component":"api","stacktrace"`)
+	})
+}
@@ -0,0 +1,178 @@
+package azalogger
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TraceIDKey    = "trace_id"
+	SpanIDKey     = "span_id"
+	TraceFlagsKey = "trace_flags"
+	SampledKey    = "sampled"
+	TraceStateKey = "trace_state"
+)
+
+// TraceConfig selects what WithContext adds besides span context IDs and flags
+type TraceConfig struct {
+	// TraceState adds the W3C trace state under trace_state when not empty
+	TraceState bool
+	// Baggage lists OpenTelemetry baggage members promoted to fields, under their own key
+	Baggage []string
+	// SpanEventLevel records entries at or above it as events of the span of WithContext,
+	// error values of error and fatal entries with span.RecordError. Empty disables it
+	SpanEventLevel LogLevel
+}
+
+// traceFields returns span context IDs and flags as key/value pairs
+func traceFields(spanCtx trace.SpanContext) []any {
+	return []any{
+		TraceIDKey, spanCtx.TraceID().String(),
+		SpanIDKey, spanCtx.SpanID().String(),
+		TraceFlagsKey, spanCtx.TraceFlags().String(),
+		SampledKey, spanCtx.IsSampled(),
+	}
+}
+
+// traceFields returns span context and allowed baggage members fields of ctx
+func (e *contextExtractor) traceFields(ctx context.Context) []any {
+	var kv []any
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if spanCtx.IsValid() {
+		if !e.native {
+			kv = e.format.traceFields(spanCtx)
+		}
+		if state := spanCtx.TraceState().String(); e.traceState && state != "" {
+			kv = append(kv, TraceStateKey, state)
+		}
+	}
+
+	if len(e.baggage) == 0 {
+		return kv
+	}
+	bag := baggage.FromContext(ctx)
+	for _, key := range e.baggage {
+		if member := bag.Member(key); member.Key() != "" {
+			kv = append(kv, key, member.Value())
+		}
+	}
+	return kv
+}
+
+// spanRecorder records entries as events of the span of WithContext
+// It's nil safe, a nil recorder records nothing
+type spanRecorder struct {
+	span  trace.Span
+	level LogLevel
+}
+
+// spanRecorder returns nil when span events are disabled or ctx has no recording span
+func (e *contextExtractor) spanRecorder(ctx context.Context) *spanRecorder {
+	if e == nil || e.spanEventLevel == "" {
+		return nil
+	}
+
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return nil
+	}
+	return &spanRecorder{span: span, level: e.spanEventLevel}
+}
+
+func (r *spanRecorder) enabled(level LogLevel) bool {
+	return r != nil && levelSeverity(level) >= levelSeverity(r.level)
+}
+
+func (r *spanRecorder) kv(level LogLevel, msg string, kv []any) {
+	if r.enabled(level) {
+		r.record(level, msg, kvToFields(kv))
+	}
+}
+
+func (r *spanRecorder) fields(level LogLevel, msg string, fields []Field) {
+	if r.enabled(level) {
+		r.record(level, msg, fields)
+	}
+}
+
+// record adds an event named msg, error values of error and fatal entries are recorded with RecordError
+func (r *spanRecorder) record(level LogLevel, msg string, fields []Field) {
+	attrs := make([]attribute.KeyValue, 0, len(fields)+1)
+	attrs = append(attrs, attribute.String(DefaultLevelKey, level.String()))
+	var errs []error
+	for _, field := range fields {
+		if field.Type == SkipType {
+			continue
+		}
+		attrs = appendSpanAttributes(attrs, "", field)
+		if err, ok := field.Interface.(error); ok {
+			errs = append(errs, err)
+		}
+	}
+
+	r.span.AddEvent(msg, trace.WithAttributes(attrs...))
+	if levelSeverity(level) < levelSeverity(ErrorLevel) {
+		return
+	}
+	for _, err := range errs {
+		r.span.RecordError(err)
+	}
+}
+
+// appendSpanAttributes flattens object fields with dotted keys
+func appendSpanAttributes(attrs []attribute.KeyValue, prefix string, f Field) []attribute.KeyValue {
+	key := prefix + f.Key
+	switch f.Type {
+	case StringType:
+		return append(attrs, attribute.String(key, f.String))
+	case IntType, Int64Type, DurationType:
+		return append(attrs, attribute.Int64(key, f.Integer))
+	case BoolType:
+		return append(attrs, attribute.Bool(key, f.Integer == 1))
+	case Float64Type:
+		return append(attrs, attribute.Float64(key, math.Float64frombits(uint64(f.Integer))))
+	case TimeType:
+		return append(attrs, attribute.String(key, f.time().UTC().Format(time.RFC3339Nano)))
+	case ObjectType:
+		fields, _ := f.Interface.([]Field)
+		for _, field := range fields {
+			if field.Type != SkipType {
+				attrs = appendSpanAttributes(attrs, key+".", field)
+			}
+		}
+		return attrs
+	default:
+		return append(attrs, spanAttribute(key, f.Interface))
+	}
+}
+
+func spanAttribute(key string, value any) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	case time.Duration:
+		return attribute.Int64(key, int64(v))
+	case time.Time:
+		return attribute.String(key, v.UTC().Format(time.RFC3339Nano))
+	case error:
+		return attribute.String(key, v.Error())
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	default:
+		return attribute.String(key, fmt.Sprintf("%+v", v))
+	}
+}
@@ -0,0 +1,222 @@
+package azalogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTraceContext(t *testing.T) context.Context {
+	t.Helper()
+
+	state, err := trace.ParseTraceState("vendor=value")
+	require.NoError(t, err)
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+		TraceState: state,
+	}))
+
+	bag, err := baggage.Parse("tenant=acme,user=bob,secret=s3cr3t")
+	require.NoError(t, err)
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+func TestContextExtractorTrace(t *testing.T) {
+	ctx := newTraceContext(t)
+
+	t.Run("should return span context IDs and flags", func(t *testing.T) {
+		var extractor *contextExtractor
+
+		assert.Equal(t, []any{
+			TraceIDKey, "01000000000000000000000000000000",
+			SpanIDKey, "0200000000000000",
+			TraceFlagsKey, "01",
+			SampledKey, true,
+		}, extractor.fields(ctx))
+	})
+
+	t.Run("should add trace state and allowed baggage members", func(t *testing.T) {
+		extractor := newContextExtractor(Config{Trace: TraceConfig{TraceState: true, Baggage: []string{"tenant", "user", "missing"}}}, Format{})
+
+		kv := extractor.fields(ctx)
+		assert.Equal(t, []any{TraceStateKey, "vendor=value", "tenant", "acme", "user", "bob"}, kv[8:])
+	})
+
+	t.Run("should return baggage without span context", func(t *testing.T) {
+		extractor := newContextExtractor(Config{Trace: TraceConfig{Baggage: []string{"tenant"}}}, Format{})
+		bag, err := baggage.Parse("tenant=acme")
+		require.NoError(t, err)
+
+		assert.Equal(t, []any{"tenant", "acme"}, extractor.fields(baggage.ContextWithBaggage(context.Background(), bag)))
+	})
+
+	t.Run("should skip IDs when attached natively", func(t *testing.T) {
+		extractor := newContextExtractor(Config{Trace: TraceConfig{TraceState: true}}, Format{})
+		extractor.native = true
+
+		assert.Equal(t, []any{TraceStateKey, "vendor=value"}, extractor.fields(ctx))
+	})
+
+	t.Run("should return nil without span context nor baggage", func(t *testing.T) {
+		extractor := newContextExtractor(Config{Trace: TraceConfig{Baggage: []string{"tenant"}}}, Format{})
+
+		assert.Nil(t, extractor.fields(context.Background()))
+	})
+}
+
+func TestWithContextTrace(t *testing.T) {
+	ctx := newTraceContext(t)
+	traceCfg := TraceConfig{TraceState: true, Baggage: []string{"tenant"}}
+
+	backends := map[string]Backend{"zap": ZapBackend, "slog": SlogBackend}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			var buff bytes.Buffer
+			logger, err := NewLogger(Config{Backend: backend, Writers: []io.Writer{&buff}, Trace: traceCfg})
+			require.NoError(t, err)
+
+			logger.WithContext(ctx).Info("traced")
+
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(buff.Bytes(), &entry))
+			assert.Equal(t, "01", entry[TraceFlagsKey])
+			assert.Equal(t, true, entry[SampledKey])
+			assert.Equal(t, "vendor=value", entry[TraceStateKey])
+			assert.Equal(t, "acme", entry["tenant"])
+			assert.NotContains(t, entry, "user")
+		})
+	}
+
+	t.Run("in-memory", func(t *testing.T) {
+		logger := NewInMemoryLogger(Config{Trace: traceCfg})
+
+		logger.WithContext(ctx).Info("traced")
+
+		assert.Equal(t, 1, logger.FilterField(SampledKey, true).FilterField(TraceStateKey, "vendor=value").
+			FilterField("tenant", "acme").Len())
+	})
+}
+
+func TestSpanEvents(t *testing.T) {
+	newLoggers := map[string]func(t *testing.T, cfg Config) Logger{
+		"zap": func(t *testing.T, cfg Config) Logger {
+			cfg.Backend, cfg.Writers = ZapBackend, []io.Writer{io.Discard}
+			logger, err := NewLogger(cfg)
+			require.NoError(t, err)
+			return logger
+		},
+		"slog": func(t *testing.T, cfg Config) Logger {
+			cfg.Backend, cfg.Writers = SlogBackend, []io.Writer{io.Discard}
+			logger, err := NewLogger(cfg)
+			require.NoError(t, err)
+			return logger
+		},
+		"in-memory": func(t *testing.T, cfg Config) Logger {
+			return NewInMemoryLogger(cfg)
+		},
+		"otel": func(t *testing.T, cfg Config) Logger {
+			logger, _ := newTestOtelLogger(t, cfg)
+			return logger
+		},
+	}
+
+	for name, newLogger := range newLoggers {
+		t.Run(name, func(t *testing.T) {
+			t.Run("should record entries above threshold as span events", func(t *testing.T) {
+				recorder := tracetest.NewSpanRecorder()
+				ctx, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test").Start(context.Background(), "op")
+				logger := newLogger(t, Config{LogLevel: DebugLevel, Trace: TraceConfig{SpanEventLevel: WarnLevel}})
+				err := errors.New("boom")
+
+				traced := logger.WithContext(ctx).With("app", "myapp")
+				traced.Info("not recorded")
+				traced.Warn("slow query", "table", "users", "latency", time.Millisecond)
+				traced.Log(ErrorLevel, "query failed", Err(err), Object("req", String("method", "GET")))
+				logger.Error("without span", "error", err)
+				span.End()
+
+				spans := recorder.Ended()
+				require.Len(t, spans, 1)
+				events := spans[0].Events()
+				require.Len(t, events, 3)
+
+				assert.Equal(t, "slow query", events[0].Name)
+				assert.ElementsMatch(t, []attribute.KeyValue{
+					attribute.String(DefaultLevelKey, "warn"),
+					attribute.String("table", "users"),
+					attribute.Int64("latency", int64(time.Millisecond)),
+				}, events[0].Attributes)
+
+				assert.Equal(t, "query failed", events[1].Name)
+				assert.ElementsMatch(t, []attribute.KeyValue{
+					attribute.String(DefaultLevelKey, "error"),
+					attribute.String(ErrorKey, "boom"),
+					attribute.String("req.method", "GET"),
+				}, events[1].Attributes)
+
+				assert.Equal(t, "exception", events[2].Name)
+				assert.Contains(t, events[2].Attributes, attribute.String("exception.message", "boom"))
+			})
+
+			t.Run("should not record span events when disabled", func(t *testing.T) {
+				recorder := tracetest.NewSpanRecorder()
+				ctx, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test").Start(context.Background(), "op")
+				logger := newLogger(t, Config{})
+
+				logger.WithContext(ctx).Error("failed", "error", errors.New("boom"))
+				span.End()
+
+				require.Len(t, recorder.Ended(), 1)
+				assert.Empty(t, recorder.Ended()[0].Events())
+			})
+		})
+	}
+}
+
+func TestSpanEventsAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations vary with the race detector")
+	}
+	for name, backend := range map[string]Backend{"zap": ZapBackend, "slog": SlogBackend} {
+		t.Run(name+"/should not copy fields below threshold", func(t *testing.T) {
+			ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "op")
+			defer span.End()
+			logger, err := NewLogger(Config{
+				Backend:  backend,
+				Writers:  []io.Writer{io.Discard},
+				Sampling: &SamplingConfig{},
+				Trace:    TraceConfig{SpanEventLevel: WarnLevel},
+			})
+			require.NoError(t, err)
+			// fields passed through the Logger interface always escape, the backend is called directly
+			var withoutFields, withFields float64
+			switch traced := logger.WithContext(ctx).(type) {
+			case *zapLogger:
+				withoutFields = testing.AllocsPerRun(100, func() { traced.Log(InfoLevel, "request") })
+				withFields = testing.AllocsPerRun(100, func() {
+					traced.Log(InfoLevel, "request", String("method", "GET"), Int("status", 200), Duration("latency", time.Millisecond))
+				})
+			case *slogLogger:
+				withoutFields = testing.AllocsPerRun(100, func() { traced.Log(InfoLevel, "request") })
+				withFields = testing.AllocsPerRun(100, func() {
+					traced.Log(InfoLevel, "request", String("method", "GET"), Int("status", 200), Duration("latency", time.Millisecond))
+				})
+			}
+			assert.Equal(t, withoutFields, withFields)
+		})
+	}
+}
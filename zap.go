@@ -2,72 +2,311 @@ package azalogger
 
 import (
 	"context"
+	"io"
+	"math"
 	"net/http"
+	"os"
+	"slices"
+	"sync"
 	"time"
 
-	"go.opentelemetry.io/otel/trace"
-
 	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
 	"go.uber.org/zap/zapcore"
 )
 
 type zapLogger struct {
-	logger *zap.SugaredLogger
-	level  *zap.AtomicLevel
+	base      *zap.Logger
+	logger    *zap.SugaredLogger
+	level     *zap.AtomicLevel
+	levels    *levelController
+	sampler   *sampler
+	async     *asyncWriter
+	closer    *outputCloser
+	redactor  *redactor
+	extractor *contextExtractor
+	span      *spanRecorder
+}
+
+var zapFieldsPool = sync.Pool{
+	New: func() any {
+		fields := make([]zap.Field, 0, 16)
+		return &fields
+	},
+}
+
+func (l *zapLogger) Debug(msg string, kv ...any) { l.logger.Debugw(msg, l.kv(DebugLevel, msg, kv)...) }
+func (l *zapLogger) Info(msg string, kv ...any)  { l.logger.Infow(msg, l.kv(InfoLevel, msg, kv)...) }
+func (l *zapLogger) Warn(msg string, kv ...any)  { l.logger.Warnw(msg, l.kv(WarnLevel, msg, kv)...) }
+func (l *zapLogger) Error(msg string, kv ...any) { l.logger.Errorw(msg, l.kv(ErrorLevel, msg, kv)...) }
+func (l *zapLogger) Fatal(msg string, kv ...any) { l.logger.Fatalw(msg, l.kv(FatalLevel, msg, kv)...) }
+
+// kv redacts kv and records them on the span of WithContext
+func (l *zapLogger) kv(level LogLevel, msg string, kv []any) []any {
+	kv = l.redactor.kv(kv)
+	l.span.kv(level, msg, kv)
+	return kv
+}
+
+// logCaller logs kv with pc as caller, see callerLogger
+func (l *zapLogger) logCaller(pc uintptr, level LogLevel, msg string, kv []any) {
+	kv = l.kv(level, msg, kv)
+	ce := l.base.Check(toZapLevel(level), msg)
+	if ce == nil {
+		return
+	}
+	if ce.Caller.Defined {
+		frame := pcFrame(pc)
+		ce.Caller = zapcore.EntryCaller{Defined: true, PC: frame.PC, File: frame.File, Line: frame.Line, Function: frame.Function}
+	}
+	// zap stack starts at the caller of logCaller, the record one is wanted
+	if ce.Stack != "" && pc != 0 {
+		ce.Stack = stacktrace(pc)
+	}
+
+	fields := kvToFields(kv)
+	zapFields := make([]zap.Field, 0, len(fields))
+	for _, field := range fields {
+		zapFields = append(zapFields, toZapField(field))
+	}
+	ce.Write(zapFields...)
 }
 
-func (l *zapLogger) Debug(msg string, kv ...any) { l.logger.Debugw(msg, kv...) }
-func (l *zapLogger) Info(msg string, kv ...any)  { l.logger.Infow(msg, kv...) }
-func (l *zapLogger) Warn(msg string, kv ...any)  { l.logger.Warnw(msg, kv...) }
-func (l *zapLogger) Error(msg string, kv ...any) { l.logger.Errorw(msg, kv...) }
-func (l *zapLogger) Fatal(msg string, kv ...any) { l.logger.Fatalw(msg, kv...) }
+func (l *zapLogger) Log(level LogLevel, msg string, fields ...Field) {
+	l.spanFields(level, msg, fields)
+	ce := l.base.Check(toZapLevel(level), msg)
+	if ce == nil {
+		return
+	}
+	l.write(ce, fields)
+}
+
+// logStack logs fields with stack as stacktrace, see stackLogger
+func (l *zapLogger) logStack(level LogLevel, msg, stack string, fields []Field) {
+	l.spanFields(level, msg, fields)
+	ce := l.base.Check(toZapLevel(level), msg)
+	if ce == nil {
+		return
+	}
+	ce.Stack = stack
+	l.write(ce, fields)
+}
+
+// spanFields records redacted fields on the span of WithContext
+func (l *zapLogger) spanFields(level LogLevel, msg string, fields []Field) {
+	if l.span.enabled(level) {
+		// cloned so fields doesn't escape when span events are off
+		redacted, _ := l.redactor.fields(slices.Clone(fields))
+		l.span.fields(level, msg, redacted)
+	}
+}
+
+func (l *zapLogger) write(ce *zapcore.CheckedEntry, fields []Field) {
+	zapFields := zapFieldsPool.Get().(*[]zap.Field)
+	for _, field := range fields {
+		field, _ = l.redactor.field(field)
+		*zapFields = append(*zapFields, toZapField(field))
+	}
+	ce.Write(*zapFields...)
+
+	clear(*zapFields)
+	*zapFields = (*zapFields)[:0]
+	zapFieldsPool.Put(zapFields)
+}
 
 func (l *zapLogger) Sync() { _ = l.logger.Sync() }
 
+// Close writes queued async entries, stops the async goroutine and closes files opened from Outputs
+// The logger and loggers derived from it must not be used afterwards
+func (l *zapLogger) Close() error { return l.closer.Close() }
+
 func (l *zapLogger) With(kv ...any) Logger {
-	return &zapLogger{logger: l.logger.With(kv...)}
+	logger := l.logger.With(l.redactor.kv(kv)...)
+	return &zapLogger{
+		base:      logger.Desugar(),
+		logger:    logger,
+		level:     l.level,
+		levels:    l.levels,
+		sampler:   l.sampler,
+		async:     l.async,
+		closer:    l.closer,
+		redactor:  l.redactor,
+		extractor: l.extractor,
+		span:      l.span,
+	}
 }
 
 func (l *zapLogger) WithContext(ctx context.Context) Logger {
-	span := trace.SpanFromContext(ctx)
-	spanCtx := span.SpanContext()
-
-	if !spanCtx.IsValid() {
+	kv := l.extractor.fields(ctx)
+	if len(kv) == 0 {
 		return l
 	}
 
-	return l.With("trace_id", spanCtx.TraceID().String(),
-		"span_id", spanCtx.SpanID().String())
+	child := l.With(kv...).(*zapLogger)
+	child.span = l.extractor.spanRecorder(ctx)
+	return child
 }
 
 func (l *zapLogger) HTTPLevelHandler(authHandler AuthorizationHandler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if authHandler != nil && !authHandler(r) {
-			http.Error(w, "unauthorized", http.StatusForbidden)
-			return
-		}
-
-		l.level.ServeHTTP(w, r)
-	})
+	return l.levels.handler(authHandler)
 }
 
 func (l *zapLogger) LogLevel() string {
 	return l.logger.Level().String()
 }
 
+func (l *zapLogger) Stats() Stats {
+	stats := l.sampler.Stats()
+	stats.AsyncDropped = l.async.Dropped()
+	return stats
+}
+
 func newZapLogger(cfg Config) (*zapLogger, error) {
 	zapCfg := createZapConfig(cfg)
-	logger, err := zapCfg.Build(zap.AddCallerSkip(1))
+	out, closer, err := openOutputs(cfg)
 	if err != nil {
 		return nil, err
 	}
 
+	format := cfg.format()
+	sampler := newSampler(cfg.Sampling)
+	async, _ := out.(*asyncWriter)
+	staticFields := format.staticFields()
+	zapFields := make([]zap.Field, 0, len(staticFields))
+	for _, field := range staticFields {
+		zapFields = append(zapFields, toZapField(field))
+	}
+	logger := buildZapLogger(zapCfg, format.newZapEncoder(zapCfg.EncoderConfig), out, sampler, format.profile).With(zapFields...)
 	return &zapLogger{
-		logger: logger.Sugar(),
-		level:  &zapCfg.Level,
+		base:      logger,
+		logger:    logger.Sugar(),
+		level:     &zapCfg.Level,
+		levels:    newZapLevelController(&zapCfg.Level),
+		sampler:   sampler,
+		async:     async,
+		closer:    closer,
+		redactor:  newRedactor(cfg.Redaction),
+		extractor: newContextExtractor(cfg, format),
 	}, nil
 }
 
+// buildZapLogger builds like zap.Config.Build, with stacktraces from error in production and from warn in development,
+// but writes to out with encoder and wraps the core with the profile and samplers
+func buildZapLogger(zapCfg zap.Config, encoder zapcore.Encoder, out io.Writer, sampler *sampler, profile Profile) *zap.Logger {
+	core := zapcore.NewCore(encoder, zapcore.Lock(zapcore.AddSync(out)), zapCfg.Level)
+	if profile == ECSProfile {
+		core = &ecsCore{Core: core}
+	}
+	if zapCfg.Sampling != nil {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, zapCfg.Sampling.Initial, zapCfg.Sampling.Thereafter)
+	}
+	if sampler != nil {
+		core = &samplingCore{Core: core, sampler: sampler}
+	}
+
+	stackLevel := zapcore.ErrorLevel
+	opts := []zap.Option{zap.ErrorOutput(zapcore.Lock(os.Stderr)), zap.WithCaller(!zapCfg.DisableCaller), zap.AddCallerSkip(1)}
+	if zapCfg.Development {
+		stackLevel = zapcore.WarnLevel
+		opts = append(opts, zap.Development())
+	}
+	opts = append(opts, zap.AddStacktrace(stackLevel))
+
+	return zap.New(core, opts...)
+}
+
+var timeFirstPool = buffer.NewPool()
+
+// timeFirstEncoder writes the time before the level like slog JSON handler, zap JSON encoder writing the level first
+type timeFirstEncoder struct {
+	zapcore.Encoder
+	time zapcore.Encoder
+}
+
+func newTimeFirstEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	timeCfg := zapcore.EncoderConfig{TimeKey: cfg.TimeKey, EncodeTime: cfg.EncodeTime, SkipLineEnding: true}
+	cfg.TimeKey = ""
+	return &timeFirstEncoder{Encoder: zapcore.NewJSONEncoder(cfg), time: zapcore.NewJSONEncoder(timeCfg)}
+}
+
+func (e *timeFirstEncoder) Clone() zapcore.Encoder {
+	return &timeFirstEncoder{Encoder: e.Encoder.Clone(), time: e.time}
+}
+
+func (e *timeFirstEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	entry, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil || ent.Time.IsZero() {
+		return entry, err
+	}
+	defer entry.Free()
+	timeObject, err := e.time.EncodeEntry(zapcore.Entry{Time: ent.Time}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer timeObject.Free()
+
+	// {"timestamp":...} without its closing brace, then the entry without its opening one
+	buf := timeFirstPool.Get()
+	buf.Write(timeObject.Bytes()[:timeObject.Len()-1])
+	rest := entry.Bytes()[1:]
+	if len(rest) > 0 && rest[0] != '}' {
+		buf.AppendByte(',')
+	}
+	buf.Write(rest)
+	return buf, nil
+}
+
+func toZapLevel(level LogLevel) zapcore.Level {
+	switch level {
+	case DebugLevel:
+		return zapcore.DebugLevel
+	case WarnLevel:
+		return zapcore.WarnLevel
+	case ErrorLevel:
+		return zapcore.ErrorLevel
+	case FatalLevel:
+		return zapcore.FatalLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+func toZapField(f Field) zap.Field {
+	switch f.Type {
+	case StringType:
+		return zap.String(f.Key, f.String)
+	case IntType, Int64Type:
+		return zap.Int64(f.Key, f.Integer)
+	case BoolType:
+		return zap.Bool(f.Key, f.Integer == 1)
+	case Float64Type:
+		return zap.Float64(f.Key, math.Float64frombits(uint64(f.Integer)))
+	case DurationType:
+		return zap.Duration(f.Key, time.Duration(f.Integer))
+	case TimeType:
+		return zap.Time(f.Key, f.time())
+	case ErrorType:
+		err, _ := f.Interface.(error)
+		return zap.NamedError(f.Key, err)
+	case ObjectType:
+		fields, _ := f.Interface.([]Field)
+		return zap.Object(f.Key, zapFieldsMarshaler(fields))
+	case SkipType:
+		return zap.Skip()
+	default:
+		return zap.Any(f.Key, f.Interface)
+	}
+}
+
+type zapFieldsMarshaler []Field
+
+func (fields zapFieldsMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, field := range fields {
+		toZapField(field).AddTo(enc)
+	}
+	return nil
+}
+
 func createZapConfig(cfg Config) zap.Config {
 	logLevel := getLogLevel(cfg)
 
@@ -80,18 +319,19 @@ func createZapConfig(cfg Config) zap.Config {
 	switch cfg.Env {
 	case DevEnvironment:
 		zapCfg = zap.NewDevelopmentConfig()
-		zapCfg.Encoding = "console"
-		zapCfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 	default:
 		zapCfg = zap.NewProductionConfig()
-		zapCfg.Encoding = "json"
+	}
+
+	if cfg.Sampling != nil {
+		zapCfg.Sampling = nil
 	}
 
 	zapCfg.Level = zap.NewAtomicLevelAt(zapLevel)
-	zapCfg.EncoderConfig.TimeKey = "timestamp"
-	zapCfg.EncoderConfig.EncodeTime = zapcore.TimeEncoder(func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-		enc.AppendString(t.UTC().Format("2006-01-02T15:04:05Z0700"))
-	})
+	zapCfg.DisableCaller = cfg.DisableCaller
+	format := cfg.format()
+	zapCfg.Encoding = string(format.Encoding)
+	format.applyZap(&zapCfg.EncoderConfig)
 
 	return zapCfg
 }
@@ -12,12 +12,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"go.uber.org/zap"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestCreateZapConfig(t *testing.T) {
 	t.Run("should create zap config based on config (dev)", func(t *testing.T) {
-		expected := zap.NewDevelopmentEncoderConfig()
 		cfg := Config{
 			LogLevel: WarnLevel,
 			Env:      DevEnvironment,
@@ -25,18 +24,16 @@ func TestCreateZapConfig(t *testing.T) {
 
 		got := createZapConfig(cfg)
 		assert.Equal(t, WarnLevel.String(), got.Level.String())
+		assert.True(t, got.Development)
 		assert.Equal(t, "console", got.Encoding)
-		assert.Equal(t, expected.MessageKey, got.EncoderConfig.MessageKey)
-		assert.Equal(t, expected.LevelKey, got.EncoderConfig.LevelKey)
-		assert.Equal(t, "timestamp", got.EncoderConfig.TimeKey)
-		assert.Equal(t, expected.NameKey, got.EncoderConfig.NameKey)
-		assert.Equal(t, expected.CallerKey, got.EncoderConfig.CallerKey)
-		assert.Equal(t, expected.FunctionKey, got.EncoderConfig.FunctionKey)
-		assert.Equal(t, expected.StacktraceKey, got.EncoderConfig.StacktraceKey)
+		assert.Equal(t, DefaultMessageKey, got.EncoderConfig.MessageKey)
+		assert.Equal(t, DefaultLevelKey, got.EncoderConfig.LevelKey)
+		assert.Equal(t, DefaultTimeKey, got.EncoderConfig.TimeKey)
+		assert.Equal(t, CallerKey, got.EncoderConfig.CallerKey)
+		assert.Equal(t, StacktraceKey, got.EncoderConfig.StacktraceKey)
 	})
 
 	t.Run("should create zap config based on config (prod)", func(t *testing.T) {
-		expected := zap.NewProductionEncoderConfig()
 		cfg := Config{
 			LogLevel: WarnLevel,
 			Env:      ProdEnvironment,
@@ -44,14 +41,13 @@ func TestCreateZapConfig(t *testing.T) {
 
 		got := createZapConfig(cfg)
 		assert.Equal(t, WarnLevel.String(), got.Level.String())
+		assert.False(t, got.Development)
 		assert.Equal(t, "json", got.Encoding)
-		assert.Equal(t, expected.MessageKey, got.EncoderConfig.MessageKey)
-		assert.Equal(t, expected.LevelKey, got.EncoderConfig.LevelKey)
-		assert.Equal(t, "timestamp", got.EncoderConfig.TimeKey)
-		assert.Equal(t, expected.NameKey, got.EncoderConfig.NameKey)
-		assert.Equal(t, expected.CallerKey, got.EncoderConfig.CallerKey)
-		assert.Equal(t, expected.FunctionKey, got.EncoderConfig.FunctionKey)
-		assert.Equal(t, expected.StacktraceKey, got.EncoderConfig.StacktraceKey)
+		assert.Equal(t, DefaultMessageKey, got.EncoderConfig.MessageKey)
+		assert.Equal(t, DefaultLevelKey, got.EncoderConfig.LevelKey)
+		assert.Equal(t, DefaultTimeKey, got.EncoderConfig.TimeKey)
+		assert.Equal(t, CallerKey, got.EncoderConfig.CallerKey)
+		assert.Equal(t, StacktraceKey, got.EncoderConfig.StacktraceKey)
 	})
 
 	t.Run("should default to info loglevel when unknown one are passed", func(t *testing.T) {
@@ -190,3 +186,52 @@ func TestHttpLevelHandler_Zap(t *testing.T) {
 		assert.Equal(t, InfoLevel.String(), logger.LogLevel())
 	})
 }
+
+func TestWith_Zap(t *testing.T) {
+	var buff bytes.Buffer
+	logger := newTestZapLogger(t, &buff, InfoLevel)
+
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanCtx)
+
+	derived := logger.With("component", "api").WithContext(ctx).With("req", 1)
+
+	t.Run("should log with injected fields", func(t *testing.T) {
+		buff.Reset()
+		derived.Debug("filtered")
+		derived.Info("info log")
+		derived.Warn("warn log")
+		derived.Error("error log")
+		derived.Log(InfoLevel, "typed log", String("foo", "bar"))
+		derived.Sync()
+
+		output := buff.String()
+		assert.NotContains(t, output, "filtered")
+		for _, msg := range []string{"info log", "warn log", "error log", "typed log"} {
+			assert.Contains(t, output, msg+`","component":"api","trace_id":"`+spanCtx.TraceID().String()+
+				`","span_id":"`+spanCtx.SpanID().String()+`","trace_flags":"00","sampled":false,"req":1`)
+		}
+	})
+
+	t.Run("should share level with parent", func(t *testing.T) {
+		assert.Equal(t, InfoLevel.String(), derived.LogLevel())
+
+		body := strings.NewReader(`{"level":"debug"}`)
+		req, err := http.NewRequest("PUT", "/loglevel", body)
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		derived.HTTPLevelHandler(nil).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, DebugLevel.String(), derived.LogLevel())
+		assert.Equal(t, DebugLevel.String(), logger.LogLevel())
+
+		buff.Reset()
+		logger.With("component", "other").Debug("now visible")
+		assert.Contains(t, buff.String(), "now visible")
+	})
+}